@@ -0,0 +1,319 @@
+package dayone
+
+import (
+	"encoding/json"
+	"github.com/juju/errgo"
+	"image/jpeg"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProblemKind identifies the kind of problem found by Check.
+type ProblemKind string
+
+const (
+	ProblemUnparseable       ProblemKind = "unparseable"
+	ProblemUnknownKey        ProblemKind = "unknown-key"
+	ProblemUUIDMismatch      ProblemKind = "uuid-mismatch"
+	ProblemMissingUUID       ProblemKind = "missing-uuid"
+	ProblemDuplicateUUID     ProblemKind = "duplicate-uuid"
+	ProblemInvalidEntry      ProblemKind = "invalid-entry"
+	ProblemEmptyEntry        ProblemKind = "empty-entry"
+	ProblemInvalidTimeZone   ProblemKind = "invalid-time-zone"
+	ProblemInvalidCoordinate ProblemKind = "invalid-coordinate"
+	ProblemOrphanedPhoto     ProblemKind = "orphaned-photo"
+	ProblemInvalidPhoto      ProblemKind = "invalid-photo"
+	ProblemConflictedCopy    ProblemKind = "conflicted-copy"
+	ProblemTempFile          ProblemKind = "temp-file"
+	ProblemStrayFile         ProblemKind = "stray-file"
+	ProblemIgnoredStepCount  ProblemKind = "ignored-step-count"
+)
+
+// Repair actions recorded on a Problem.
+const (
+	RepairQuarantined = "quarantined"
+	RepairRemoved     = "removed"
+	RepairFixed       = "fixed"
+)
+
+// Problem is a single problem found by Check.
+type Problem struct {
+	Kind    ProblemKind `json:"kind"`
+	Path    string      `json:"path"` // relative to the journal dir
+	UUID    string      `json:"uuid,omitempty"`
+	Message string      `json:"message"`
+
+	// Repair is what repair mode did about the problem,
+	// or empty if it was left alone.
+	Repair string `json:"repair,omitempty"`
+
+	// Warning is true for oddities that Day One itself
+	// writes and that don't stop the entry being written.
+	Warning bool `json:"warning,omitempty"`
+}
+
+// CheckReport is the result of Journal.Check.
+type CheckReport struct {
+	Entries  int       `json:"entries"`
+	Photos   int       `json:"photos"`
+	Problems []Problem `json:"problems"`
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *CheckReport) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// CheckOptions configures Journal.Check.
+type CheckOptions struct {
+	// Repair fixes the problems that can be fixed safely:
+	// unparseable entries and invalid photos are moved to
+	// the quarantine dir, leftover temp files are removed
+	// and missing plist UUIDs are filled in from the file
+	// name. Everything else is only reported.
+	Repair bool
+
+	// QuarantineDir is where broken files are moved, keeping
+	// their path relative to the journal. Defaults to
+	// ".quarantine" inside the journal dir.
+	QuarantineDir string
+}
+
+// Check walks the whole journal and reports anything wrong
+// with its entries and photos. opts may be nil.
+func (j *Journal) Check(opts *CheckOptions) (*CheckReport, error) {
+	var o CheckOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.QuarantineDir == "" {
+		o.QuarantineDir = filepath.Join(j.dir, ".quarantine")
+	}
+
+	if o.Repair {
+		unlock, err := j.lock()
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	c := &checker{j: j, opts: o, report: &CheckReport{}}
+
+	entries, err := c.checkEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.checkPhotos(entries); err != nil {
+		return nil, err
+	}
+
+	return c.report, nil
+}
+
+type checker struct {
+	j      *Journal
+	opts   CheckOptions
+	report *CheckReport
+}
+
+func (c *checker) add(p Problem) {
+	c.report.Problems = append(c.report.Problems, p)
+}
+
+// checkFile reports temp files, conflicted copies and stray
+// files. It returns false if name isn't a normal file.
+func (c *checker) checkFile(rel, name string, isNormal func(string) bool) bool {
+	switch {
+	case isTempFile(name):
+		p := Problem{Kind: ProblemTempFile, Path: rel, Message: "leftover temp file from an interrupted write"}
+		if c.opts.Repair {
+			if err := os.Remove(filepath.Join(c.j.dir, rel)); err == nil {
+				p.Repair = RepairRemoved
+			}
+		}
+		c.add(p)
+	case isConflictedCopy(name):
+		c.add(Problem{Kind: ProblemConflictedCopy, Path: rel, Message: "Dropbox conflicted copy"})
+	case !isNormal(name):
+		if name != ".gitkeep" && name != ".DS_Store" {
+			c.add(Problem{Kind: ProblemStrayFile, Path: rel, Message: "not an entry or photo file"})
+		}
+	default:
+		return true
+	}
+	return false
+}
+
+// checkEntries checks every entry file and returns the set
+// of entry uuids found.
+func (c *checker) checkEntries() (map[string]bool, error) {
+	uuids := make(map[string]bool)
+
+	files, err := ioutil.ReadDir(c.j.getEntriesDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return uuids, nil
+		}
+		return nil, errgo.Mask(err)
+	}
+
+	seen := make(map[string]string)
+	for _, f := range files {
+		rel := filepath.Join("entries", f.Name())
+		if f.IsDir() || !c.checkFile(rel, f.Name(), isEntryFile) {
+			continue
+		}
+
+		uuid := strings.TrimSuffix(f.Name(), filepath.Ext(f.Name()))
+		uuids[uuid] = true
+		c.report.Entries++
+
+		e, err := c.j.readChecked(uuid, seen)
+		if e == nil {
+			c.checkUnreadable(rel, uuid, err)
+			continue
+		}
+
+		if err != nil {
+			c.checkUUID(rel, e, err)
+		}
+		c.checkEntry(rel, e)
+	}
+
+	return uuids, nil
+}
+
+func (c *checker) checkUnreadable(rel, uuid string, err error) {
+	if _, ok := err.(*UnknownKeyError); ok {
+		// The entry is fine, just newer than this package.
+		c.add(Problem{Kind: ProblemUnknownKey, Path: rel, UUID: uuid, Message: err.Error()})
+		return
+	}
+
+	p := Problem{Kind: ProblemUnparseable, Path: rel, UUID: uuid, Message: err.Error()}
+	if c.opts.Repair && c.quarantine(rel, uuid) == nil {
+		p.Repair = RepairQuarantined
+	}
+	c.add(p)
+}
+
+func (c *checker) checkUUID(rel string, e *Entry, err error) {
+	p := Problem{Path: rel, UUID: e.UUID(), Message: err.Error()}
+
+	switch err.(type) {
+	case *UUIDMismatchError:
+		p.Kind = ProblemUUIDMismatch
+	case *DuplicateUUIDError:
+		p.Kind = ProblemDuplicateUUID
+	case *MissingUUIDError:
+		p.Kind = ProblemMissingUUID
+		if !c.opts.Repair {
+			break
+		}
+		// Writing an entry that is invalid in other ways, e.g.
+		// one with no date, would make up values for it.
+		if verr := e.validateWrite(); verr != nil {
+			p.Message += "; not repaired: " + verr.Error()
+		} else if c.j.write(e) == nil {
+			p.Repair = RepairFixed
+		}
+	}
+
+	c.add(p)
+}
+
+func (c *checker) checkEntry(rel string, e *Entry) {
+	uuid := e.UUID()
+
+	if err, ok := e.validate().(*ValidationError); ok {
+		for _, p := range err.Problems {
+			kind := ProblemInvalidEntry
+			switch {
+			case p.Field == "TimeZone":
+				kind = ProblemInvalidTimeZone
+			case p.Field == "StepCount":
+				kind = ProblemIgnoredStepCount
+			case strings.HasSuffix(p.Field, ".Latitude") || strings.HasSuffix(p.Field, ".Longitude"):
+				kind = ProblemInvalidCoordinate
+			}
+			c.add(Problem{Kind: kind, Path: rel, UUID: uuid, Message: p.Message, Warning: p.Warning})
+		}
+	}
+
+	if strings.TrimSpace(e.EntryText) == "" {
+		if _, err := c.j.PhotoStat(uuid); err != nil {
+			c.add(Problem{Kind: ProblemEmptyEntry, Path: rel, UUID: uuid, Message: "entry has no text or photo"})
+		}
+	}
+}
+
+// checkPhotos checks every photo file. entries is the set of
+// entry uuids used to find orphaned photos.
+func (c *checker) checkPhotos(entries map[string]bool) error {
+	files, err := ioutil.ReadDir(c.j.getPhotosDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errgo.Mask(err)
+	}
+
+	for _, f := range files {
+		rel := filepath.Join("photos", f.Name())
+		if f.IsDir() || !c.checkFile(rel, f.Name(), isPhotoFile) {
+			continue
+		}
+
+		uuid := strings.TrimSuffix(f.Name(), filepath.Ext(f.Name()))
+		c.report.Photos++
+
+		if !entries[uuid] {
+			c.add(Problem{Kind: ProblemOrphanedPhoto, Path: rel, UUID: uuid, Message: "photo has no entry"})
+		}
+
+		if err := checkJPEG(filepath.Join(c.j.dir, rel)); err != nil {
+			p := Problem{Kind: ProblemInvalidPhoto, Path: rel, UUID: uuid, Message: err.Error()}
+			if c.opts.Repair && c.quarantine(rel, uuid) == nil {
+				p.Repair = RepairQuarantined
+			}
+			c.add(p)
+		}
+	}
+
+	return nil
+}
+
+func checkJPEG(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = jpeg.DecodeConfig(f)
+	return err
+}
+
+// quarantine moves the file at rel, relative to the journal
+// dir, to the same relative path in the quarantine dir. The
+// file is kept as a version of uuid first, so restoring it
+// doesn't depend on the quarantine dir.
+func (c *checker) quarantine(rel, uuid string) error {
+	target := filepath.Join(c.opts.QuarantineDir, rel)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return c.j.versioned(uuid, func() error {
+		return renameFile(filepath.Join(c.j.dir, rel), target)
+	})
+}
@@ -0,0 +1,220 @@
+package dayone
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckCleanJournal(t *testing.T) {
+	j := NewJournal("./test_journals/default")
+
+	report, err := j.Check(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Entries != 2 || report.Photos != 1 {
+		t.Errorf("unexpected counts: %+v", report)
+	}
+
+	// Day One keeps step counts it was told to ignore,
+	// which is only worth a warning.
+	for _, p := range report.Problems {
+		if p.Kind != ProblemIgnoredStepCount || !p.Warning {
+			t.Errorf("unexpected problem: %+v", p)
+		}
+	}
+	if len(report.Problems) != 2 {
+		t.Errorf("expected 2 warnings, got %+v", report.Problems)
+	}
+}
+
+// brokenJournal returns a copy of the default journal with
+// one of each kind of problem Check looks for.
+func brokenJournal(t *testing.T) (*Journal, func()) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+
+	write := func(rel, data string) {
+		if err := ioutil.WriteFile(filepath.Join(j.dir, rel), []byte(data), 0644); err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(j.getEntriesDir(), "FF755C6D7D9B4A5FBC4E41C07D622C65"+entryExt))
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	entry := string(b)
+
+	write("entries/11111111111111111111111111111111.doentry", "not a plist")
+	write("entries/22222222222222222222222222222222.doentry",
+		strings.Replace(entry, "<key>Activity</key>", "<key>Mood</key>", 1))
+	write("entries/.33333333333333333333333333333333.doentry.tmp1", "partial")
+	write("entries/notes.txt", "hello")
+
+	// Write doesn't allow invalid entries so encode it directly.
+	e := &Entry{
+		uuid:     "44444444444444444444444444444444",
+		TimeZone: "Mars/Olympus_Mons",
+		Location: &Location{Coordinate: Coordinate{Latitude: 91}},
+	}
+	if err := writeFileAtomic(filepath.Join(j.getEntriesDir(), e.uuid+entryExt), e.encode); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	write("photos/55555555555555555555555555555555.jpg", "not a jpeg")
+
+	return j, cleanup
+}
+
+func problemKinds(report *CheckReport) map[ProblemKind]Problem {
+	kinds := make(map[ProblemKind]Problem)
+	for _, p := range report.Problems {
+		kinds[p.Kind] = p
+	}
+	return kinds
+}
+
+func TestCheckFindsProblems(t *testing.T) {
+	j, cleanup := brokenJournal(t)
+	defer cleanup()
+
+	report, err := j.Check(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := problemKinds(report)
+	for _, k := range []ProblemKind{
+		ProblemUnparseable,
+		ProblemUnknownKey,
+		ProblemTempFile,
+		ProblemStrayFile,
+		ProblemInvalidEntry,
+		ProblemEmptyEntry,
+		ProblemInvalidTimeZone,
+		ProblemInvalidCoordinate,
+		ProblemOrphanedPhoto,
+		ProblemInvalidPhoto,
+		ProblemIgnoredStepCount,
+	} {
+		p, ok := kinds[k]
+		if !ok {
+			t.Errorf("expected a %s problem", k)
+		} else if p.Repair != "" {
+			t.Errorf("%s: unexpected repair without repair mode", k)
+		}
+	}
+
+	if p := kinds[ProblemUnknownKey]; p.UUID != "22222222222222222222222222222222" {
+		t.Errorf("unexpected unknown key problem: %+v", p)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded CheckReport
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Problems) != len(report.Problems) {
+		t.Error("json report lost problems")
+	}
+}
+
+func TestCheckRepair(t *testing.T) {
+	j, cleanup := brokenJournal(t)
+	defer cleanup()
+
+	report, err := j.Check(&CheckOptions{Repair: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := problemKinds(report)
+	if kinds[ProblemUnparseable].Repair != RepairQuarantined {
+		t.Error("expected unparseable entry to be quarantined")
+	}
+	if kinds[ProblemInvalidPhoto].Repair != RepairQuarantined {
+		t.Error("expected invalid photo to be quarantined")
+	}
+	if kinds[ProblemTempFile].Repair != RepairRemoved {
+		t.Error("expected temp file to be removed")
+	}
+	if kinds[ProblemUnknownKey].Repair != "" {
+		t.Error("expected unknown key entry to be left alone")
+	}
+
+	quarantined := filepath.Join(j.dir, ".quarantine", "entries", "11111111111111111111111111111111.doentry")
+	if _, err := os.Stat(quarantined); err != nil {
+		t.Error(err)
+	}
+
+	report, err = j.Check(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds = problemKinds(report)
+	for _, k := range []ProblemKind{ProblemUnparseable, ProblemInvalidPhoto, ProblemTempFile} {
+		if _, ok := kinds[k]; ok {
+			t.Errorf("%s still reported after repair", k)
+		}
+	}
+}
+
+func TestCheckRepairMissingUUID(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	const fixable = "66666666666666666666666666666666"
+	const undated = "77777777777777777777777777777777"
+	copyEntryFile(t, j, fixable, true)
+	copyEntryFile(t, j, undated, true)
+
+	path := filepath.Join(j.getEntriesDir(), undated+entryExt)
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = []byte(strings.Replace(string(b), "<key>Creation Date</key>\n\t<date>2014-09-24T01:52:11Z</date>", "", 1))
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := j.Check(&CheckOptions{Repair: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repairs := make(map[string]string)
+	for _, p := range report.Problems {
+		if p.Kind == ProblemMissingUUID {
+			repairs[p.UUID] = p.Repair
+		}
+	}
+	if repairs[fixable] != RepairFixed {
+		t.Errorf("expected %s to be fixed, got %q", fixable, repairs[fixable])
+	}
+	if r, ok := repairs[undated]; !ok || r != "" {
+		t.Errorf("expected %s to be reported and left alone, got %q", undated, r)
+	}
+
+	after, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(after, b) {
+		t.Error("entry with no date was rewritten")
+	}
+}
@@ -0,0 +1,296 @@
+package dayone
+
+import (
+	"errors"
+	"github.com/juju/errgo"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// conflictedCopyPattern matches the names Dropbox gives
+// conflicted copies, e.g.
+//
+//	FF755C6D7D9B4A5FBC4E41C07D622C65 (Joshua's conflicted copy 2014-09-24).doentry
+//	FF755C6D7D9B4A5FBC4E41C07D622C65 (conflicted copy 2014-09-24 (1)).jpg
+var conflictedCopyPattern = regexp.MustCompile(`(?i)^(.+?) \((?:(.+)'s )?conflicted copy(?: (\d{4}-\d{2}-\d{2}))?(?: \(\d+\))?\)(\.[^.]+)$`)
+
+func isConflictedCopy(name string) bool {
+	return conflictedCopyPattern.MatchString(name)
+}
+
+// ConflictedCopy is a Dropbox conflicted copy of an
+// entry or photo file.
+type ConflictedCopy struct {
+	Name string    // file name of the copy
+	Host string    // device that made the copy, if known
+	Date time.Time // date from the file name, if any
+}
+
+// Conflict groups the conflicted copies of an entry
+// or photo with the original file.
+type Conflict struct {
+	UUID        string
+	Photo       bool // true for a photo, false for an entry
+	HasOriginal bool // false if only copies exist
+	Copies      []ConflictedCopy
+}
+
+// ResolveStrategy chooses how ResolveConflict picks
+// the version to keep.
+type ResolveStrategy int
+
+const (
+	// KeepNewest keeps the most recently modified version.
+	KeepNewest ResolveStrategy = iota
+
+	// KeepOriginal keeps the original file, or the newest
+	// copy if there is no original.
+	KeepOriginal
+
+	// MergeCopies keeps the original entry with the tags of
+	// every copy added and the text of any copy that differs
+	// appended. Photos can't be merged so KeepNewest is
+	// used for them, as it is for entries with no original.
+	MergeCopies
+)
+
+// Conflicts finds the Dropbox conflicted copies of entries
+// and photos in the journal, grouped by uuid.
+func (j *Journal) Conflicts() ([]*Conflict, error) {
+	var conflicts []*Conflict
+
+	for _, photo := range []bool{false, true} {
+		dir, ext := j.getEntriesDir(), entryExt
+		if photo {
+			dir, ext = j.getPhotosDir(), photoExt
+		}
+
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, errgo.Mask(err)
+		}
+
+		byUUID := make(map[string]*Conflict)
+		for _, f := range files {
+			m := conflictedCopyPattern.FindStringSubmatch(f.Name())
+			if m == nil || f.IsDir() || !strings.EqualFold(m[4], ext) {
+				continue
+			}
+
+			c, ok := byUUID[m[1]]
+			if !ok {
+				c = &Conflict{UUID: m[1], Photo: photo}
+				_, err := os.Stat(filepath.Join(dir, m[1]+ext))
+				c.HasOriginal = err == nil
+				byUUID[m[1]] = c
+				conflicts = append(conflicts, c)
+			}
+
+			cc := ConflictedCopy{Name: f.Name(), Host: m[2]}
+			if m[3] != "" {
+				cc.Date, _ = time.Parse("2006-01-02", m[3])
+			}
+			c.Copies = append(c.Copies, cc)
+		}
+	}
+
+	return conflicts, nil
+}
+
+func (j *Journal) conflictPaths(c *Conflict) (original string, copies []string) {
+	dir, ext := j.getEntriesDir(), entryExt
+	if c.Photo {
+		dir, ext = j.getPhotosDir(), photoExt
+	}
+
+	for _, cc := range c.Copies {
+		copies = append(copies, filepath.Join(dir, cc.Name))
+	}
+	return filepath.Join(dir, c.UUID+ext), copies
+}
+
+// ConflictDiff compares each conflicted copy to the original
+// and returns the field changes keyed by copy file name.
+// For photos a single "Photo" field with the SHA-1 of each
+// file is reported when the contents differ.
+func (j *Journal) ConflictDiff(c *Conflict) (map[string][]FieldChange, error) {
+	original, copies := j.conflictPaths(c)
+	diffs := make(map[string][]FieldChange)
+
+	if c.Photo {
+		oldHash, err := hashFile(original)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for i, path := range copies {
+			newHash, err := hashFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if newHash != oldHash {
+				diffs[c.Copies[i].Name] = []FieldChange{{Field: "Photo", Old: oldHash, New: newHash}}
+			}
+		}
+		return diffs, nil
+	}
+
+	var orig *Entry
+	if c.HasOriginal {
+		var err error
+		if orig, err = readEntryFile(original); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, path := range copies {
+		e, err := readEntryFile(path)
+		if err != nil {
+			return nil, err
+		}
+		diffs[c.Copies[i].Name] = DiffEntries(orig, e)
+	}
+
+	return diffs, nil
+}
+
+// ResolveConflict resolves the conflict using strategy s,
+// leaving a single file named after the uuid and removing
+// every conflicted copy.
+func (j *Journal) ResolveConflict(c *Conflict, s ResolveStrategy) error {
+	if len(c.Copies) == 0 {
+		return errors.New("conflict has no copies")
+	}
+
+	unlock, err := j.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	original, copies := j.conflictPaths(c)
+
+	if s == MergeCopies && !c.Photo && c.HasOriginal {
+		if err := j.mergeConflict(c.UUID, copies); err != nil {
+			return err
+		}
+		return removeFiles(copies)
+	}
+
+	keep := original
+	if s == KeepNewest || !c.HasOriginal {
+		if keep, err = newestFile(append([]string{original}, copies...)); err != nil {
+			return err
+		}
+	}
+
+	var losers []string
+	for _, path := range copies {
+		if path != keep {
+			losers = append(losers, path)
+		}
+	}
+
+	return j.versioned(c.UUID, func() error {
+		if keep != original {
+			if err := renameFile(keep, original); err != nil {
+				return err
+			}
+		}
+		return removeFiles(losers)
+	})
+}
+
+// mergeConflict merges the tags and text of each copy
+// into the original entry. The caller must hold the lock.
+func (j *Journal) mergeConflict(uuid string, copies []string) error {
+	e, err := j.ReadEntry(uuid)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range copies {
+		c, err := readEntryFile(path)
+		if err != nil {
+			return err
+		}
+
+		e.Tags = uniqueTags(append(e.Tags, c.Tags...))
+
+		switch {
+		case strings.Contains(e.EntryText, c.EntryText):
+			// Nothing new in the copy.
+		case strings.Contains(c.EntryText, e.EntryText):
+			// The copy only added to the text.
+			e.EntryText = c.EntryText
+		default:
+			e.EntryText += "\n\n" + c.EntryText
+		}
+	}
+
+	if err := e.validateWrite(); err != nil {
+		return err
+	}
+
+	return j.write(e)
+}
+
+// newestFile returns the most recently modified of paths,
+// ignoring any that don't exist.
+func newestFile(paths []string) (string, error) {
+	var newest string
+	var newestTime time.Time
+
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return "", errgo.Mask(err)
+		}
+
+		if newest == "" || fi.ModTime().After(newestTime) {
+			newest, newestTime = path, fi.ModTime()
+		}
+	}
+
+	if newest == "" {
+		return "", errors.New("no versions found")
+	}
+	return newest, nil
+}
+
+// renameFile moves the file at from to to, replacing any file
+// there, and syncs the dirs so the rename survives a crash.
+func renameFile(from, to string) error {
+	if err := os.Rename(from, to); err != nil {
+		return errgo.Mask(err)
+	}
+	syncDir(filepath.Dir(to))
+	if filepath.Dir(from) != filepath.Dir(to) {
+		syncDir(filepath.Dir(from))
+	}
+	return nil
+}
+
+// removeFiles removes each of paths that exists and syncs
+// their dirs so the removals survive a crash.
+func removeFiles(paths []string) error {
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return errgo.Mask(err)
+		}
+		dirs[filepath.Dir(path)] = true
+	}
+	for dir := range dirs {
+		syncDir(dir)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package dayone
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const conflictedEntryName = "FF755C6D7D9B4A5FBC4E41C07D622C65 (Joshua's iPhone's conflicted copy 2014-09-24).doentry"
+
+// addConflictedCopy writes a conflicted copy of the FF755C6D
+// entry with different text and tags and a newer mtime.
+func addConflictedCopy(t *testing.T, j *Journal) {
+	e, err := j.ReadEntry("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.EntryText = "from the other device"
+	e.Tags = []string{"bjj", "judo"}
+
+	path := filepath.Join(j.getEntriesDir(), conflictedEntryName)
+	if err := writeFileAtomic(path, e.encode); err != nil {
+		t.Fatal(err)
+	}
+
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsConflictedCopy(t *testing.T) {
+	names := map[string]bool{
+		conflictedEntryName: true,
+		"871D0F435D7B469C9429CD441A9E74B5 (conflicted copy 2014-09-24 (1)).jpg": true,
+		"871D0F435D7B469C9429CD441A9E74B5 (Joshua's conflicted copy).doentry":   true,
+		"871D0F435D7B469C9429CD441A9E74B5.doentry":                              false,
+	}
+
+	for name, expected := range names {
+		if isConflictedCopy(name) != expected {
+			t.Errorf("%s: expected %v", name, expected)
+		}
+	}
+}
+
+func TestReadSkipsConflictedCopies(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	addConflictedCopy(t, j)
+
+	count := 0
+	err := j.Read(func(e *Entry, err error) error {
+		count++
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 2 {
+		t.Errorf("expected 2 entries, read %v", count)
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	addConflictedCopy(t, j)
+
+	conflicts, err := j.Conflicts()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %v", len(conflicts))
+	}
+
+	c := conflicts[0]
+	if c.UUID != "FF755C6D7D9B4A5FBC4E41C07D622C65" || c.Photo || !c.HasOriginal {
+		t.Errorf("unexpected conflict: %+v", c)
+	}
+
+	if len(c.Copies) != 1 || c.Copies[0].Host != "Joshua's iPhone" || c.Copies[0].Date.Format("2006-01-02") != "2014-09-24" {
+		t.Errorf("unexpected copies: %+v", c.Copies)
+	}
+
+	diffs, err := j.ConflictDiff(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := diffs[conflictedEntryName]
+	if len(changes) != 2 || changes[0].Field != "EntryText" || changes[1].Field != "Tags" {
+		t.Errorf("unexpected diff: %v", changes)
+	}
+}
+
+func resolveTestConflict(t *testing.T, s ResolveStrategy) *Entry {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	addConflictedCopy(t, j)
+
+	conflicts, err := j.Conflicts()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := j.ResolveConflict(conflicts[0], s); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ioutil.ReadDir(j.getEntriesDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if isConflictedCopy(f.Name()) {
+			t.Errorf("conflicted copy left behind: %s", f.Name())
+		}
+	}
+
+	e, err := j.ReadEntry("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestResolveConflictKeepNewest(t *testing.T) {
+	e := resolveTestConflict(t, KeepNewest)
+	if e.EntryText != "from the other device" {
+		t.Error("expected newest copy to be kept")
+	}
+}
+
+func TestResolveConflictKeepOriginal(t *testing.T) {
+	e := resolveTestConflict(t, KeepOriginal)
+	if e.EntryText == "from the other device" {
+		t.Error("expected original to be kept")
+	}
+}
+
+func TestResolveConflictMerge(t *testing.T) {
+	e := resolveTestConflict(t, MergeCopies)
+
+	if !reflect.DeepEqual(e.Tags, []string{"bjj", "fitness", "judo"}) {
+		t.Errorf("unexpected tags: %v", e.Tags)
+	}
+
+	expected := "#title line\n\nbody line\n\nfrom the other device"
+	if e.EntryText != expected {
+		t.Errorf("unexpected text: %q", e.EntryText)
+	}
+}
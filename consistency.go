@@ -0,0 +1,80 @@
+package dayone
+
+import (
+	"path/filepath"
+)
+
+// UUIDMismatchError is passed to a ReadFunc when the uuid in
+// an entry's file name doesn't match its plist UUID key.
+type UUIDMismatchError struct {
+	File      string
+	FileUUID  string
+	PlistUUID string
+}
+
+func (e *UUIDMismatchError) Error() string {
+	return "uuid mismatch: file " + e.File + " has plist uuid " + e.PlistUUID
+}
+
+// MissingUUIDError is passed to a ReadFunc when an entry
+// has no plist UUID key. The entry's uuid is taken from
+// the file name.
+type MissingUUIDError struct {
+	File string
+}
+
+func (e *MissingUUIDError) Error() string {
+	return "missing uuid: " + e.File
+}
+
+// DuplicateUUIDError is passed to a ReadFunc when an entry
+// has the same uuid as an entry already read from another file.
+type DuplicateUUIDError struct {
+	UUID      string
+	File      string
+	FirstFile string // the file the uuid was first read from
+}
+
+func (e *DuplicateUUIDError) Error() string {
+	return "duplicate uuid " + e.UUID + ": " + e.File + " and " + e.FirstFile
+}
+
+// resolveUUID picks the entry's uuid from the plist or the
+// file name uuid depending on TrustFilenameUUID. Entries
+// without a plist UUID always use the file name.
+func (j *Journal) resolveUUID(e *Entry, fileUUID string) {
+	if e.uuid == "" || j.TrustFilenameUUID {
+		e.uuid = fileUUID
+	}
+}
+
+// readChecked reads the entry with the file name uuid and
+// checks it against the plist UUID and the uuids in seen,
+// which maps uuids already read to their file name.
+func (j *Journal) readChecked(uuid string, seen map[string]string) (*Entry, error) {
+	file := uuid + entryExt
+
+	e, err := readEntryFile(filepath.Join(j.getEntriesDir(), file))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case e.uuid == "":
+		err = &MissingUUIDError{File: file}
+	case e.uuid != uuid:
+		err = &UUIDMismatchError{File: file, FileUUID: uuid, PlistUUID: e.uuid}
+	}
+
+	j.resolveUUID(e, uuid)
+
+	if first, ok := seen[e.uuid]; ok {
+		if err == nil {
+			err = &DuplicateUUIDError{UUID: e.uuid, File: file, FirstFile: first}
+		}
+	} else {
+		seen[e.uuid] = file
+	}
+
+	return e, err
+}
@@ -0,0 +1,112 @@
+package dayone
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const copiedEntryUUID = "00000000000000000000000000000000"
+
+// copyEntryFile copies the FF755C6D entry file to a file
+// named after uuid, optionally dropping its plist UUID key.
+func copyEntryFile(t *testing.T, j *Journal, uuid string, dropUUID bool) {
+	b, err := ioutil.ReadFile(filepath.Join(j.getEntriesDir(), "FF755C6D7D9B4A5FBC4E41C07D622C65"+entryExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := string(b)
+	if dropUUID {
+		s = strings.Replace(s, "<key>UUID</key>\n\t<string>FF755C6D7D9B4A5FBC4E41C07D622C65</string>", "", 1)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(j.getEntriesDir(), uuid+entryExt), []byte(s), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readAll reads every entry in j and returns their
+// uuids and the errors passed to the ReadFunc.
+func readAll(t *testing.T, j *Journal) ([]string, []error) {
+	var uuids []string
+	var errs []error
+	err := j.Read(func(e *Entry, err error) error {
+		uuids = append(uuids, e.UUID())
+		errs = append(errs, err)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(uuids) != 3 {
+		t.Fatalf("expected 3 entries, got %v", len(uuids))
+	}
+	return uuids, errs
+}
+
+func TestReadReportsMismatchAndDuplicate(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	copyEntryFile(t, j, copiedEntryUUID, false)
+
+	_, errs := readAll(t, j)
+
+	if m, ok := errs[0].(*UUIDMismatchError); !ok || m.FileUUID != copiedEntryUUID || m.PlistUUID != "FF755C6D7D9B4A5FBC4E41C07D622C65" {
+		t.Errorf("expected mismatch error, got %v", errs[0])
+	}
+
+	if errs[1] != nil {
+		t.Errorf("unexpected error: %v", errs[1])
+	}
+
+	if d, ok := errs[2].(*DuplicateUUIDError); !ok || d.FirstFile != copiedEntryUUID+entryExt {
+		t.Errorf("expected duplicate error, got %v", errs[2])
+	}
+}
+
+func TestReadTrustingFilenameUUID(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	copyEntryFile(t, j, copiedEntryUUID, false)
+	j.TrustFilenameUUID = true
+
+	uuids, errs := readAll(t, j)
+
+	if uuids[0] != copiedEntryUUID {
+		t.Errorf("expected file name uuid, got %v", uuids[0])
+	}
+
+	if _, ok := errs[0].(*UUIDMismatchError); !ok {
+		t.Errorf("expected mismatch error, got %v", errs[0])
+	}
+
+	if errs[2] != nil {
+		t.Errorf("expected no duplicate error, got %v", errs[2])
+	}
+}
+
+func TestReadReportsMissingUUID(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	copyEntryFile(t, j, copiedEntryUUID, true)
+
+	uuids, errs := readAll(t, j)
+
+	if _, ok := errs[0].(*MissingUUIDError); !ok {
+		t.Errorf("expected missing uuid error, got %v", errs[0])
+	}
+
+	if uuids[0] != copiedEntryUUID {
+		t.Errorf("expected file name uuid, got %v", uuids[0])
+	}
+
+	if errs[2] != nil {
+		t.Errorf("unexpected error: %v", errs[2])
+	}
+}
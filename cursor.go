@@ -0,0 +1,185 @@
+package dayone
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/juju/errgo"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// FileVersion is the state of an entry or photo file
+// when a Cursor was taken.
+type FileVersion struct {
+	ModTime time.Time `json:"modTime"`
+	Size    int64     `json:"size"`
+	Hash    string    `json:"hash"` // hex SHA-1 of the contents
+}
+
+// Cursor is a snapshot of every entry and photo in a journal,
+// keyed by uuid. Save it after processing a journal and pass
+// it to Journal.ChangesSince on the next run.
+type Cursor struct {
+	Time    time.Time              `json:"time"`
+	Entries map[string]FileVersion `json:"entries"`
+	Photos  map[string]FileVersion `json:"photos"`
+}
+
+// Changes lists the uuids that changed between two cursors.
+// Each list is sorted.
+type Changes struct {
+	AddedEntries   []string
+	ChangedEntries []string
+	RemovedEntries []string
+
+	AddedPhotos   []string
+	ChangedPhotos []string
+	RemovedPhotos []string
+}
+
+// Empty reports whether there were no changes at all.
+func (c *Changes) Empty() bool {
+	return len(c.AddedEntries)+len(c.ChangedEntries)+len(c.RemovedEntries)+
+		len(c.AddedPhotos)+len(c.ChangedPhotos)+len(c.RemovedPhotos) == 0
+}
+
+// Checkpoint takes a new Cursor for the journal.
+func (j *Journal) Checkpoint() (*Cursor, error) {
+	return j.checkpoint(&Cursor{})
+}
+
+// ChangesSince takes a new Cursor for the journal and returns
+// what was added, changed and removed since the since cursor
+// along with the new cursor. A nil since treats everything
+// as added.
+//
+// A file only counts as changed when its contents do. Files
+// with the same mtime and size as in since aren't re-read.
+func (j *Journal) ChangesSince(since *Cursor) (*Changes, *Cursor, error) {
+	if since == nil {
+		since = &Cursor{}
+	}
+
+	c, err := j.checkpoint(since)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changes := &Changes{}
+	changes.AddedEntries, changes.ChangedEntries, changes.RemovedEntries = diffVersions(since.Entries, c.Entries)
+	changes.AddedPhotos, changes.ChangedPhotos, changes.RemovedPhotos = diffVersions(since.Photos, c.Photos)
+
+	return changes, c, nil
+}
+
+// checkpoint takes a new Cursor, reusing hashes from prev
+// for files whose mtime and size haven't changed.
+func (j *Journal) checkpoint(prev *Cursor) (*Cursor, error) {
+	files, err := j.scanFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cursor{
+		Time:    time.Now(),
+		Entries: make(map[string]FileVersion),
+		Photos:  make(map[string]FileVersion),
+	}
+
+	for k, s := range files {
+		versions, old := c.Entries, prev.Entries
+		path := filepath.Join(j.getEntriesDir(), k.uuid+entryExt)
+		if k.photo {
+			versions, old = c.Photos, prev.Photos
+			path = filepath.Join(j.getPhotosDir(), k.uuid+photoExt)
+		}
+
+		v := FileVersion{ModTime: s.modTime, Size: s.size}
+		if o, ok := old[k.uuid]; ok && o.ModTime.Equal(v.ModTime) && o.Size == v.Size {
+			v.Hash = o.Hash
+		} else {
+			v.Hash, err = hashFile(path)
+			if os.IsNotExist(err) {
+				// Removed since it was scanned.
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+		}
+
+		versions[k.uuid] = v
+	}
+
+	return c, nil
+}
+
+func diffVersions(old, new map[string]FileVersion) (added, changed, removed []string) {
+	for uuid, v := range new {
+		o, ok := old[uuid]
+		if !ok {
+			added = append(added, uuid)
+		} else if o.Hash != v.Hash {
+			changed = append(changed, uuid)
+		}
+	}
+	for uuid := range old {
+		if _, ok := new[uuid]; !ok {
+			removed = append(removed, uuid)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", err
+		} else {
+			return "", errgo.Mask(err)
+		}
+	}
+	defer f.Close()
+
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", errgo.Mask(err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// LoadCursor reads a cursor saved with Cursor.Save.
+func LoadCursor(path string) (*Cursor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		} else {
+			return nil, errgo.Mask(err)
+		}
+	}
+	defer f.Close()
+
+	c := &Cursor{}
+	if err := json.NewDecoder(f).Decode(c); err != nil {
+		return nil, errgo.Mask(err)
+	}
+
+	return c, nil
+}
+
+// Save writes the cursor to path as JSON.
+func (c *Cursor) Save(path string) error {
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(c)
+	})
+}
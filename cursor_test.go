@@ -0,0 +1,100 @@
+package dayone
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChangesSince(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	changes, c, err := j.ChangesSince(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"871D0F435D7B469C9429CD441A9E74B5", "FF755C6D7D9B4A5FBC4E41C07D622C65"}
+	if !reflect.DeepEqual(changes.AddedEntries, expected) {
+		t.Errorf("unexpected added entries: %v", changes.AddedEntries)
+	}
+	if !reflect.DeepEqual(changes.AddedPhotos, expected[:1]) {
+		t.Errorf("unexpected added photos: %v", changes.AddedPhotos)
+	}
+
+	changes, c, err = j.ChangesSince(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changes.Empty() {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+
+	e, err := j.ReadEntry("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.EntryText = "changed"
+	if err := j.Write(e); err != nil {
+		t.Fatal(err)
+	}
+
+	// Touching a file without changing it isn't a change.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(j.getEntriesDir(), "871D0F435D7B469C9429CD441A9E74B5"+entryExt), later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(filepath.Join(j.getPhotosDir(), "871D0F435D7B469C9429CD441A9E74B5"+photoExt)); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.WritePhoto("FF755C6D7D9B4A5FBC4E41C07D622C65", strings.NewReader("jpeg")); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, _, err = j.ChangesSince(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Changes{
+		ChangedEntries: []string{"FF755C6D7D9B4A5FBC4E41C07D622C65"},
+		AddedPhotos:    []string{"FF755C6D7D9B4A5FBC4E41C07D622C65"},
+		RemovedPhotos:  []string{"871D0F435D7B469C9429CD441A9E74B5"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("actual: %+v, expected: %+v", changes, want)
+	}
+}
+
+func TestCursorSaveAndLoad(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	c, err := j.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(j.dir, "cursor.json")
+	if err := c.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadCursor(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, _, err := j.ChangesSince(loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changes.Empty() {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+}
@@ -0,0 +1,483 @@
+package dayone
+
+import (
+	"archive/zip"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/juju/errgo"
+	"image/jpeg"
+	"io"
+	"math"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dayOne2Journal is the Journal.json in a Day One 2 export.
+type dayOne2Journal struct {
+	Metadata struct {
+		Version string `json:"version"`
+	} `json:"metadata"`
+	Entries []*dayOne2Entry `json:"entries"`
+}
+
+type dayOne2Entry struct {
+	UUID                string           `json:"uuid"`
+	CreationDate        time.Time        `json:"creationDate"`
+	TimeZone            string           `json:"timeZone,omitempty"`
+	Text                string           `json:"text"`
+	Starred             bool             `json:"starred"`
+	Tags                []string         `json:"tags,omitempty"`
+	CreationDevice      string           `json:"creationDevice,omitempty"`
+	CreationDeviceType  string           `json:"creationDeviceType,omitempty"`
+	CreationDeviceModel string           `json:"creationDeviceModel,omitempty"`
+	CreationOSName      string           `json:"creationOSName,omitempty"`
+	CreationOSVersion   string           `json:"creationOSVersion,omitempty"`
+	Location            *dayOne2Location `json:"location,omitempty"`
+	Weather             *dayOne2Weather  `json:"weather,omitempty"`
+	Music               *dayOne2Music    `json:"music,omitempty"`
+	UserActivity        *dayOne2Activity `json:"userActivity,omitempty"`
+	Photos              []*dayOne2Photo  `json:"photos,omitempty"`
+}
+
+type dayOne2Location struct {
+	PlaceName          string         `json:"placeName,omitempty"`
+	LocalityName       string         `json:"localityName,omitempty"`
+	AdministrativeArea string         `json:"administrativeArea,omitempty"`
+	Country            string         `json:"country,omitempty"`
+	FoursquareID       string         `json:"foursquareID,omitempty"`
+	Latitude           float64        `json:"latitude"`
+	Longitude          float64        `json:"longitude"`
+	Region             *dayOne2Region `json:"region,omitempty"`
+}
+
+type dayOne2Region struct {
+	Center *struct {
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+	} `json:"center,omitempty"`
+	Radius float64 `json:"radius,omitempty"`
+}
+
+type dayOne2Weather struct {
+	ConditionsDescription string     `json:"conditionsDescription,omitempty"`
+	TemperatureCelsius    *float64   `json:"temperatureCelsius,omitempty"`
+	PressureMB            float64    `json:"pressureMB,omitempty"`
+	RelativeHumidity      float64    `json:"relativeHumidity,omitempty"`
+	WeatherServiceName    string     `json:"weatherServiceName,omitempty"`
+	SunriseDate           *time.Time `json:"sunriseDate,omitempty"`
+	SunsetDate            *time.Time `json:"sunsetDate,omitempty"`
+	VisibilityKM          float64    `json:"visibilityKM,omitempty"`
+	WindBearing           float64    `json:"windBearing,omitempty"`
+	WindChillCelsius      float64    `json:"windChillCelsius,omitempty"`
+	WindSpeedKPH          float64    `json:"windSpeedKPH,omitempty"`
+}
+
+type dayOne2Music struct {
+	Album     string `json:"album,omitempty"`
+	Artist    string `json:"artist,omitempty"`
+	Track     string `json:"track,omitempty"`
+	AlbumYear string `json:"albumYear,omitempty"`
+}
+
+type dayOne2Activity struct {
+	ActivityName string `json:"activityName,omitempty"`
+	StepCount    uint64 `json:"stepCount,omitempty"`
+}
+
+type dayOne2Photo struct {
+	Identifier   string `json:"identifier"`
+	MD5          string `json:"md5"`
+	Type         string `json:"type"`
+	OrderInEntry int    `json:"orderInEntry"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+}
+
+// momentPattern matches the links Day One 2 puts in entry
+// text to show a photo, e.g. ![](dayone-moment://0E1A...).
+var momentPattern = regexp.MustCompile(`!\[[^\]]*\]\(dayone-moment:/+([0-9A-Fa-f]+)\)\n?`)
+
+// DayOne2Archive is a Day One 2 export zip opened with
+// OpenDayOne2. Close it when done.
+type DayOne2Archive struct {
+	// Entries are the entries in the export. Classic entries
+	// have at most one photo, so each gets the first photo
+	// from the export and the photo links are removed from
+	// its text.
+	Entries []*Entry
+
+	zip    *zip.ReadCloser
+	photos map[string]*zip.File // keyed by entry uuid
+}
+
+// OpenDayOne2 opens a Day One 2 export zip and reads its
+// entries. The journal JSON is Journal.json, or the first
+// JSON file at the top of the zip if there is no Journal.json.
+func OpenDayOne2(path string) (*DayOne2Archive, error) {
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		} else {
+			return nil, errgo.Mask(err)
+		}
+	}
+
+	a := &DayOne2Archive{zip: z, photos: make(map[string]*zip.File)}
+	if err := a.read(); err != nil {
+		z.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *DayOne2Archive) read() error {
+	var journal *zip.File
+	photos := make(map[string]*zip.File) // keyed by md5
+	for _, f := range a.zip.File {
+		dir, name := path.Split(f.Name)
+		switch {
+		case dir == "" && name == "Journal.json":
+			journal = f
+		case dir == "" && path.Ext(name) == ".json" && journal == nil:
+			journal = f
+		case dir == "photos/" && name != "":
+			photos[strings.ToLower(strings.TrimSuffix(name, path.Ext(name)))] = f
+		}
+	}
+	if journal == nil {
+		return errors.New("no journal JSON in Day One 2 export")
+	}
+
+	r, err := journal.Open()
+	if err != nil {
+		return errgo.Mask(err)
+	}
+	defer r.Close()
+
+	var j dayOne2Journal
+	if err := json.NewDecoder(r).Decode(&j); err != nil {
+		return errgo.Notef(err, "invalid %s", journal.Name)
+	}
+
+	for _, de := range j.Entries {
+		e := de.entry()
+		if len(de.Photos) > 0 {
+			first := de.Photos[0]
+			for _, p := range de.Photos[1:] {
+				if p.OrderInEntry < first.OrderInEntry {
+					first = p
+				}
+			}
+			if f, ok := photos[strings.ToLower(first.MD5)]; ok {
+				a.photos[e.uuid] = f
+			}
+		}
+		a.Entries = append(a.Entries, e)
+	}
+	return nil
+}
+
+// OpenPhoto opens the photo for the entry uuid, like
+// Journal.OpenPhoto. It returns an error satisfying
+// os.IsNotExist if the entry has no photo.
+func (a *DayOne2Archive) OpenPhoto(uuid string) (io.ReadCloser, error) {
+	f, ok := a.photos[uuid]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: "photos/" + uuid, Err: os.ErrNotExist}
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	return r, nil
+}
+
+// Import writes every entry and photo in the archive to j,
+// replacing any with the same uuid. It returns the number
+// of entries written.
+func (a *DayOne2Archive) Import(j *Journal) (int, error) {
+	unlock, err := j.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	for i, e := range a.Entries {
+		if err := j.writeEntry(e); err != nil {
+			return i, errgo.Notef(err, "entry %s", e.uuid)
+		}
+
+		r, err := a.OpenPhoto(e.uuid)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return i, err
+		}
+		err = j.writePhoto(e.uuid, r)
+		r.Close()
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(a.Entries), nil
+}
+
+// Close closes the zip file.
+func (a *DayOne2Archive) Close() error {
+	return a.zip.Close()
+}
+
+// entry converts a Day One 2 entry to a Classic entry.
+func (de *dayOne2Entry) entry() *Entry {
+	e := &Entry{
+		uuid:         strings.ToUpper(de.UUID),
+		CreationDate: de.CreationDate.UTC(),
+		TimeZone:     de.TimeZone,
+		EntryText:    de.Text,
+		Starred:      de.Starred,
+		Tags:         de.Tags,
+	}
+	if momentPattern.MatchString(e.EntryText) {
+		e.EntryText = strings.TrimSpace(momentPattern.ReplaceAllString(e.EntryText, ""))
+	}
+
+	if de.CreationDevice != "" || de.CreationOSName != "" || de.CreationDeviceType != "" {
+		e.Creator = &Creator{
+			HostName:    de.CreationDevice,
+			DeviceAgent: joinNonEmpty("/", de.CreationDeviceType, de.CreationDeviceModel),
+			OSAgent:     joinNonEmpty("/", de.CreationOSName, de.CreationOSVersion),
+		}
+	}
+
+	if l := de.Location; l != nil {
+		e.Location = &Location{
+			PlaceName:          l.PlaceName,
+			Locality:           l.LocalityName,
+			AdministrativeArea: l.AdministrativeArea,
+			Country:            l.Country,
+			FoursquareID:       l.FoursquareID,
+			Coordinate:         Coordinate{l.Latitude, l.Longitude},
+		}
+		if r := l.Region; r != nil {
+			e.Location.Region = &Region{Radius: r.Radius}
+			if c := r.Center; c != nil {
+				e.Location.Region.Center = &Coordinate{c.Latitude, c.Longitude}
+			}
+		}
+	}
+
+	if w := de.Weather; w != nil {
+		e.Weather = &Weather{
+			Description:      w.ConditionsDescription,
+			PressureMB:       w.PressureMB,
+			RelativeHumidity: w.RelativeHumidity,
+			Service:          w.WeatherServiceName,
+			SunriseDate:      timeOrZero(w.SunriseDate),
+			SunsetDate:       timeOrZero(w.SunsetDate),
+			VisibilityKM:     w.VisibilityKM,
+			WindBearing:      uint64(math.Max(w.WindBearing, 0)),
+			WindChillCelsius: int64(math.Round(w.WindChillCelsius)),
+			WindSpeedKPH:     w.WindSpeedKPH,
+		}
+		if c := w.TemperatureCelsius; c != nil {
+			e.Weather.Celsius = formatDegrees(*c)
+			e.Weather.Fahrenheit = formatDegrees(*c*9/5 + 32)
+		}
+	}
+
+	if m := de.Music; m != nil {
+		e.Music = &Music{Album: m.Album, Artist: m.Artist, Track: m.Track, AlbumYear: m.AlbumYear}
+	}
+
+	if a := de.UserActivity; a != nil {
+		e.Activity = a.ActivityName
+		e.StepCount = a.StepCount
+	}
+
+	return e
+}
+
+// newDayOne2Entry converts a Classic entry to a Day One 2 entry.
+func newDayOne2Entry(e *Entry) *dayOne2Entry {
+	de := &dayOne2Entry{
+		UUID:         e.uuid,
+		CreationDate: e.CreationDate.UTC(),
+		TimeZone:     e.TimeZone,
+		Text:         e.EntryText,
+		Starred:      e.Starred,
+		Tags:         e.Tags,
+	}
+
+	if c := e.Creator; c != nil {
+		de.CreationDevice = c.HostName
+		de.CreationDeviceType, de.CreationDeviceModel = splitAgent(c.DeviceAgent)
+		de.CreationOSName, de.CreationOSVersion = splitAgent(c.OSAgent)
+	}
+
+	if l := e.Location; l != nil {
+		de.Location = &dayOne2Location{
+			PlaceName:          l.PlaceName,
+			LocalityName:       l.Locality,
+			AdministrativeArea: l.AdministrativeArea,
+			Country:            l.Country,
+			FoursquareID:       l.FoursquareID,
+			Latitude:           l.Latitude,
+			Longitude:          l.Longitude,
+		}
+		if r := l.Region; r != nil {
+			de.Location.Region = &dayOne2Region{Radius: r.Radius}
+			if c := r.Center; c != nil {
+				de.Location.Region.Center = &struct {
+					Latitude  float64 `json:"latitude"`
+					Longitude float64 `json:"longitude"`
+				}{c.Latitude, c.Longitude}
+			}
+		}
+	}
+
+	if w := e.Weather; w != nil {
+		de.Weather = &dayOne2Weather{
+			ConditionsDescription: w.Description,
+			PressureMB:            w.PressureMB,
+			RelativeHumidity:      w.RelativeHumidity,
+			WeatherServiceName:    w.Service,
+			SunriseDate:           optionalTime(w.SunriseDate),
+			SunsetDate:            optionalTime(w.SunsetDate),
+			VisibilityKM:          w.VisibilityKM,
+			WindBearing:           float64(w.WindBearing),
+			WindChillCelsius:      float64(w.WindChillCelsius),
+			WindSpeedKPH:          w.WindSpeedKPH,
+		}
+		if c, err := strconv.ParseFloat(w.Celsius, 64); err == nil {
+			de.Weather.TemperatureCelsius = &c
+		}
+	}
+
+	if m := e.Music; m != nil {
+		de.Music = &dayOne2Music{Album: m.Album, Artist: m.Artist, Track: m.Track, AlbumYear: m.AlbumYear}
+	}
+
+	if e.Activity != "" || e.StepCount != 0 {
+		de.UserActivity = &dayOne2Activity{ActivityName: e.Activity, StepCount: e.StepCount}
+	}
+
+	return de
+}
+
+// splitAgent splits an agent string like "iOS/8.0".
+func splitAgent(agent string) (name, version string) {
+	parts := strings.SplitN(agent, "/", 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return agent, ""
+}
+
+func formatDegrees(f float64) string {
+	return strconv.FormatFloat(math.Round(f), 'f', -1, 64)
+}
+
+// ExportDayOne2 writes the entries matching filter to w as a
+// Day One 2 export zip that the Day One app can import. Each
+// entry's photo is stored in the photos dir named by its MD5
+// and linked at the top of the entry text. A nil filter
+// exports every entry. It returns the number of entries written.
+//
+// Classic fields with no Day One 2 equivalent are left out:
+// PublishURL, IgnoreStepCount, the weather icon and the
+// creator's software agent and generation date.
+func (j *Journal) ExportDayOne2(w io.Writer, filter FilterFunc) (int, error) {
+	entries, err := j.exportEntries(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	zw := zip.NewWriter(w)
+	written := make(map[string]bool)
+	var journal dayOne2Journal
+	journal.Metadata.Version = "1.0"
+
+	for _, e := range entries {
+		de := newDayOne2Entry(e)
+
+		photo, err := j.exportDayOne2Photo(zw, e.uuid, written)
+		if err != nil {
+			return 0, err
+		}
+		if photo != nil {
+			de.Photos = []*dayOne2Photo{photo}
+			de.Text = "![](dayone-moment://" + photo.Identifier + ")\n" + de.Text
+		}
+
+		journal.Entries = append(journal.Entries, de)
+	}
+
+	f, err := zw.Create("Journal.json")
+	if err != nil {
+		return 0, errgo.Mask(err)
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(journal); err != nil {
+		return 0, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return 0, errgo.Mask(err)
+	}
+	return len(entries), nil
+}
+
+// exportDayOne2Photo adds the photo for uuid to the zip unless
+// written says an identical one is already there, and returns
+// its description, or nil if the entry has no photo.
+func (j *Journal) exportDayOne2Photo(zw *zip.Writer, uuid string, written map[string]bool) (*dayOne2Photo, error) {
+	p := &dayOne2Photo{Identifier: newUUID(), Type: "jpeg"}
+
+	// Read the photo once for its hash and size, since
+	// the zip entry name has to come first.
+	r, err := j.OpenPhoto(uuid)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	h := md5.New()
+	if cfg, err := jpeg.DecodeConfig(io.TeeReader(r, h)); err == nil {
+		p.Width, p.Height = cfg.Width, cfg.Height
+	}
+	_, err = io.Copy(h, r)
+	r.Close()
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	p.MD5 = hex.EncodeToString(h.Sum(nil))
+
+	if written[p.MD5] {
+		return p, nil
+	}
+	written[p.MD5] = true
+
+	r, err = j.OpenPhoto(uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	f, err := zw.Create("photos/" + p.MD5 + ".jpeg")
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		return nil, errgo.Mask(err)
+	}
+	return p, nil
+}
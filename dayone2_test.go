@@ -0,0 +1,181 @@
+package dayone
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// exportDayOne2 exports the default journal to a zip
+// in dir and returns its path.
+func exportDayOne2(t *testing.T, dir string) string {
+	path := filepath.Join(dir, "export.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	n, err := NewJournal("./test_journals/default").ExportDayOne2(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 entries, got %d", n)
+	}
+	return path
+}
+
+func TestDayOne2RoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dayone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a, err := OpenDayOne2(exportDayOne2(t, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	if len(a.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(a.Entries))
+	}
+
+	src := NewJournal("./test_journals/default")
+	for _, e := range a.Entries {
+		orig, err := src.ReadEntry(e.UUID())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var fields []string
+		for _, c := range DiffEntries(orig, e) {
+			fields = append(fields, c.Field)
+		}
+		expected := []string{
+			"IgnoreStepCount",
+			"Weather.IconName",
+			"Creator.GenerationDate",
+			"Creator.SoftwareAgent",
+		}
+		if !reflect.DeepEqual(fields, expected) {
+			t.Errorf("unexpected changes to %s: %v", e.UUID(), DiffEntries(orig, e))
+		}
+	}
+
+	r, err := a.OpenPhoto("871D0F435D7B469C9429CD441A9E74B5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig, err := ioutil.ReadFile("./test_journals/default/photos/871D0F435D7B469C9429CD441A9E74B5.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(orig) {
+		t.Error("photo changed in round trip")
+	}
+
+	if _, err := a.OpenPhoto("FF755C6D7D9B4A5FBC4E41C07D622C65"); !os.IsNotExist(err) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+}
+
+func TestDayOne2ExportLayout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dayone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	z, err := zip.OpenReader(exportDayOne2(t, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer z.Close()
+
+	var names []string
+	var journal string
+	for _, f := range z.File {
+		names = append(names, f.Name)
+		if f.Name == "Journal.json" {
+			r, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := ioutil.ReadAll(r)
+			r.Close()
+			journal = string(b)
+		}
+	}
+
+	if len(names) != 2 || !strings.HasPrefix(names[0], "photos/") || !strings.HasSuffix(names[0], ".jpeg") {
+		t.Errorf("unexpected files: %v", names)
+	}
+	md5 := strings.TrimSuffix(strings.TrimPrefix(names[0], "photos/"), ".jpeg")
+
+	for _, s := range []string{
+		`"md5": "` + md5 + `"`,
+		`"text": "![](dayone-moment://`,
+		`"temperatureCelsius": 24`,
+		`"userActivity": {`,
+		`"creationOSName": "iOS"`,
+	} {
+		if !strings.Contains(journal, s) {
+			t.Errorf("expected Journal.json to contain %q", s)
+		}
+	}
+}
+
+func TestDayOne2Import(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dayone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a, err := OpenDayOne2(exportDayOne2(t, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	j, cleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanup()
+
+	n, err := a.Import(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 imported, got %d", n)
+	}
+	if _, err := j.PhotoStat("871D0F435D7B469C9429CD441A9E74B5"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDayOne2Entry(t *testing.T) {
+	de := &dayOne2Entry{
+		UUID: "0e1a",
+		Text: "![](dayone-moment://ABCD)\n![](dayone-moment:/EF01)\nhello",
+	}
+
+	e := de.entry()
+	if e.UUID() != "0E1A" {
+		t.Errorf("unexpected uuid %q", e.UUID())
+	}
+	if e.EntryText != "hello" {
+		t.Errorf("unexpected text %q", e.EntryText)
+	}
+}
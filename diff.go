@@ -0,0 +1,171 @@
+package dayone
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldChange is a single field that differs between
+// two versions of an entry. Field is the dotted path
+// to the field, e.g. "Location.Country".
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// DiffEntries compares a and b field by field and returns
+// the fields that differ. Nested types are flattened so
+// a missing Location on one side shows up as a change
+// to each of the Location fields set on the other.
+func DiffEntries(a, b *Entry) []FieldChange {
+	oldFields := flattenEntry(a)
+	newFields := flattenEntry(b)
+
+	var changes []FieldChange
+	for _, f := range entryFieldNames {
+		o, oldOK := oldFields[f]
+		n, newOK := newFields[f]
+
+		// A field under a nil pointer is missing rather than
+		// zero, so only its formatted value can be compared.
+		changed := o.value != n.value
+		if oldOK && newOK {
+			changed = !equalValues(o.raw, n.raw)
+		}
+
+		if changed {
+			changes = append(changes, FieldChange{
+				Field: f,
+				Old:   o.value,
+				New:   n.value,
+			})
+		}
+	}
+
+	return changes
+}
+
+// entryFieldNames is the flattened name of every Entry
+// field in declaration order.
+var entryFieldNames = func() []string {
+	var names []string
+	for _, f := range flattenValue("", reflect.ValueOf(Entry{}), true) {
+		names = append(names, f.name)
+	}
+	return names
+}()
+
+// field is a flattened field with its value formatted
+// for display and the raw value for comparing.
+type field struct {
+	name  string
+	value string
+	raw   reflect.Value
+}
+
+func flattenEntry(e *Entry) map[string]field {
+	out := make(map[string]field)
+	if e == nil {
+		return out
+	}
+
+	for _, f := range flattenValue("", reflect.ValueOf(e).Elem(), false) {
+		out[f.name] = f
+	}
+	out["UUID"] = field{"UUID", e.uuid, reflect.ValueOf(e.uuid)}
+	return out
+}
+
+// flattenValue returns the fields of the struct v named by
+// their dotted path, in declaration order. When all is true
+// nil pointers are walked as zero values so every field
+// name is returned.
+func flattenValue(prefix string, v reflect.Value, all bool) []field {
+	var out []field
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+		name := prefix + sf.Name
+
+		switch {
+		case sf.Anonymous:
+			out = append(out, flattenValue(prefix, fv, all)...)
+		case sf.PkgPath != "":
+			// The uuid value is filled in by flattenEntry,
+			// other unexported fields aren't entry data.
+			if sf.Name == "uuid" {
+				out = append(out, field{name: "UUID"})
+			}
+		case fv.Kind() == reflect.Ptr:
+			if fv.IsNil() {
+				if !all {
+					continue
+				}
+				fv = reflect.New(sf.Type.Elem())
+			}
+			out = append(out, flattenValue(name+".", fv.Elem(), all)...)
+		default:
+			out = append(out, field{name, formatValue(fv), fv})
+		}
+	}
+
+	return out
+}
+
+func formatValue(v reflect.Value) string {
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339Nano)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case reflect.Slice:
+		var parts []string
+		for i := 0; i < v.Len(); i++ {
+			parts = append(parts, formatValue(v.Index(i)))
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	return ""
+}
+
+// equalValues reports whether two values of the same field
+// are equal. Times are equal if they are the same instant,
+// and slices if their elements are equal.
+func equalValues(a, b reflect.Value) bool {
+	if ta, ok := a.Interface().(time.Time); ok {
+		return ta.Equal(b.Interface().(time.Time))
+	}
+
+	if a.Kind() == reflect.Slice {
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equalValues(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return a.Interface() == b.Interface()
+}
@@ -0,0 +1,73 @@
+package dayone
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffEntriesEqual(t *testing.T) {
+	j := NewJournal("./test_journals/default")
+
+	e, err := j.ReadEntry("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changes := DiffEntries(e, e.clone()); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
+
+func TestDiffEntries(t *testing.T) {
+	a := &Entry{
+		uuid:     "FF755C6D7D9B4A5FBC4E41C07D622C65",
+		TimeZone: "America/Chicago",
+		Tags:     []string{"bjj"},
+	}
+
+	b := a.clone()
+	b.TimeZone = "America/New_York"
+	b.Tags = append(b.Tags, "fitness")
+	b.Location = &Location{Country: "United States"}
+
+	expected := []FieldChange{
+		{Field: "Tags", Old: "bjj", New: "bjj, fitness"},
+		{Field: "Location.Country", Old: "", New: "United States"},
+		{Field: "Location.Latitude", Old: "", New: "0"},
+		{Field: "Location.Longitude", Old: "", New: "0"},
+		{Field: "TimeZone", Old: "America/Chicago", New: "America/New_York"},
+	}
+
+	changes := DiffEntries(a, b)
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("actual: %v, expected: %v", changes, expected)
+	}
+}
+
+func TestDiffEntriesComparesValues(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	created := time.Date(2014, 9, 24, 1, 52, 11, 0, time.UTC)
+	a := &Entry{CreationDate: created, Tags: []string{"a, b"}}
+
+	b := a.clone()
+	b.CreationDate = created.In(chicago)
+	b.Tags = []string{"a, b"}
+	if changes := DiffEntries(a, b); len(changes) != 0 {
+		t.Errorf("expected the same instant in another zone to be equal, got %v", changes)
+	}
+
+	b.CreationDate = created.Add(500 * time.Millisecond)
+	b.Tags = []string{"a", "b"}
+	expected := []FieldChange{
+		{Field: "Tags", Old: "a, b", New: "a, b"},
+		{Field: "CreationDate", Old: "2014-09-24T01:52:11Z", New: "2014-09-24T01:52:11.5Z"},
+	}
+	if changes := DiffEntries(a, b); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("actual: %v, expected: %v", changes, expected)
+	}
+}
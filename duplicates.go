@@ -0,0 +1,250 @@
+package dayone
+
+import (
+	"errors"
+	"hash/fnv"
+	"math/bits"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DuplicateOptions configures Journal.FindDuplicates.
+// The zero value uses the defaults.
+type DuplicateOptions struct {
+	// MaxDistance is how many bits the simhashes of two
+	// entries' text may differ by for them to count as near
+	// duplicates. Defaults to 6. Set it negative to only
+	// find exact duplicates.
+	MaxDistance int
+
+	// Window is how far apart the CreationDates of two
+	// entries may be for them to count as duplicates.
+	// Defaults to 24 hours.
+	Window time.Duration
+}
+
+// DuplicateGroup is a set of entries that look like
+// copies of each other.
+type DuplicateGroup struct {
+	// UUIDs are the entries in the group, oldest first.
+	UUIDs []string
+
+	// Exact is true when every entry in the group has the
+	// same text once whitespace and case are normalized.
+	Exact bool
+}
+
+// defaultMaxDistance is low enough that unrelated texts,
+// whose simhashes differ in about half their bits, never match.
+const defaultMaxDistance = 6
+
+type fingerprint struct {
+	uuid    string
+	created time.Time
+	exact   string
+	simhash uint64
+}
+
+// FindDuplicates fingerprints the text of every entry and
+// groups entries that were created within opts.Window of
+// each other and have the same or nearly the same text.
+// Entries without text, and those Read passes with a
+// consistency error, are ignored. opts may be nil.
+func (j *Journal) FindDuplicates(opts *DuplicateOptions) ([]DuplicateGroup, error) {
+	var o DuplicateOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.MaxDistance == 0 {
+		o.MaxDistance = defaultMaxDistance
+	}
+	if o.Window <= 0 {
+		o.Window = 24 * time.Hour
+	}
+
+	var prints []fingerprint
+	err := j.Read(func(e *Entry, err error) error {
+		if e == nil {
+			return err
+		} else if err != nil {
+			// Merging by an untrusted uuid could delete the
+			// wrong file. Check reports it.
+			return nil
+		}
+
+		text := normalizeText(e.EntryText)
+		if text == "" {
+			return nil
+		}
+
+		prints = append(prints, fingerprint{
+			uuid:    e.UUID(),
+			created: e.CreationDate,
+			exact:   text,
+			simhash: simhash(text),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(prints, func(a, b int) bool {
+		return prints[a].created.Before(prints[b].created)
+	})
+
+	// Union the matching pairs into groups. Since prints is
+	// sorted by date only the ones within the window of
+	// each other need comparing.
+	parent := make([]int, len(prints))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for a := range prints {
+		for b := a + 1; b < len(prints) && prints[b].created.Sub(prints[a].created) <= o.Window; b++ {
+			if prints[a].exact == prints[b].exact ||
+				bits.OnesCount64(prints[a].simhash^prints[b].simhash) <= o.MaxDistance {
+				parent[find(b)] = find(a)
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	var roots []int
+	for i := range prints {
+		r := find(i)
+		if _, ok := members[r]; !ok {
+			roots = append(roots, r)
+		}
+		members[r] = append(members[r], i)
+	}
+
+	var groups []DuplicateGroup
+	for _, r := range roots {
+		if len(members[r]) < 2 {
+			continue
+		}
+
+		g := DuplicateGroup{Exact: true}
+		for _, i := range members[r] {
+			g.UUIDs = append(g.UUIDs, prints[i].uuid)
+			if prints[i].exact != prints[r].exact {
+				g.Exact = false
+			}
+		}
+		groups = append(groups, g)
+	}
+
+	return groups, nil
+}
+
+// MergeDuplicates merges a group of duplicates into its oldest
+// entry and returns that entry's uuid. The surviving entry
+// gets the tags of every entry in the group, is starred if any
+// of them were, and takes the first photo found if it has none.
+// The other entries and their photos are deleted.
+func (j *Journal) MergeDuplicates(g DuplicateGroup) (string, error) {
+	if len(g.UUIDs) < 2 {
+		return "", errors.New("duplicate group needs at least two entries")
+	}
+
+	unlock, err := j.lock()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	survivor, err := j.ReadEntry(g.UUIDs[0])
+	if err != nil {
+		return "", err
+	}
+
+	_, err = j.PhotoStat(survivor.UUID())
+	hasPhoto := err == nil
+	photoFrom := ""
+
+	for _, uuid := range g.UUIDs[1:] {
+		e, err := j.ReadEntry(uuid)
+		if err != nil {
+			return "", err
+		}
+
+		survivor.Tags = uniqueTags(append(survivor.Tags, e.Tags...))
+		survivor.Starred = survivor.Starred || e.Starred
+
+		if !hasPhoto && photoFrom == "" {
+			if _, err := j.PhotoStat(uuid); err == nil {
+				photoFrom = uuid
+			}
+		}
+	}
+
+	if err := j.writeEntry(survivor); err != nil {
+		return "", err
+	}
+
+	if photoFrom != "" {
+		if _, err := j.copyPhoto(j, photoFrom, survivor.UUID(), false); err != nil {
+			return "", err
+		}
+	}
+
+	for _, uuid := range g.UUIDs[1:] {
+		if err := j.delete(uuid); err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+
+	return survivor.UUID(), nil
+}
+
+// normalizeText lowercases text and collapses whitespace.
+func normalizeText(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// simhash returns a 64-bit simhash of the three word shingles
+// in text, so similar texts have hashes that differ in few bits.
+func simhash(text string) uint64 {
+	words := strings.Fields(text)
+
+	var shingles []string
+	if len(words) < 3 {
+		shingles = words
+	}
+	for i := 0; i+3 <= len(words); i++ {
+		shingles = append(shingles, strings.Join(words[i:i+3], " "))
+	}
+
+	var weights [64]int
+	for _, s := range shingles {
+		h := fnv.New64a()
+		h.Write([]byte(s))
+		sum := h.Sum64()
+		for bit := uint(0); bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var hash uint64
+	for bit := uint(0); bit < 64; bit++ {
+		if weights[bit] > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash
+}
@@ -0,0 +1,113 @@
+package dayone
+
+import (
+	"math/bits"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const longText = `Rolled at the gym tonight with the usual crew. Worked on
+guard retention drills for most of the class, then did five rounds of
+positional sparring from half guard. Felt slow on the first two rounds
+but found my timing later on. Need to remember to keep my elbows tight
+and frame earlier when passing pressure comes. Stretched afterwards and
+drove home listening to a podcast about recovery and sleep.`
+
+func TestSimhashNearDuplicates(t *testing.T) {
+	a := simhash(normalizeText(longText))
+	b := simhash(normalizeText(longText + " Great night."))
+	c := simhash(normalizeText("Completely unrelated words about cooking pasta with garlic and olive oil for dinner tonight."))
+
+	if d := bits.OnesCount64(a ^ b); d > defaultMaxDistance {
+		t.Errorf("expected near duplicates, distance %v", d)
+	}
+
+	if d := bits.OnesCount64(a ^ c); d <= defaultMaxDistance {
+		t.Errorf("expected different texts, distance %v", d)
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	created := time.Date(2015, 1, 1, 12, 0, 0, 0, time.UTC)
+	near := []*Entry{
+		{EntryText: longText, CreationDate: created},
+		{EntryText: longText + " Great night.", CreationDate: created.Add(time.Hour)},
+		// Same text but too long after the others.
+		{EntryText: longText, CreationDate: created.Add(72 * time.Hour)},
+	}
+	for _, e := range near {
+		if err := j.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	groups, err := j.FindDuplicates(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %+v", groups)
+	}
+
+	exact := DuplicateGroup{
+		UUIDs: []string{"871D0F435D7B469C9429CD441A9E74B5", "FF755C6D7D9B4A5FBC4E41C07D622C65"},
+		Exact: true,
+	}
+	if !reflect.DeepEqual(groups[0], exact) {
+		t.Errorf("unexpected exact group: %+v", groups[0])
+	}
+
+	nearGroup := DuplicateGroup{
+		UUIDs: []string{near[0].UUID(), near[1].UUID()},
+		Exact: false,
+	}
+	if !reflect.DeepEqual(groups[1], nearGroup) {
+		t.Errorf("unexpected near group: %+v", groups[1])
+	}
+}
+
+func TestMergeDuplicates(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	e, err := j.ReadEntry("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Tags = append(e.Tags, "judo")
+	if err := j.Write(e); err != nil {
+		t.Fatal(err)
+	}
+
+	survivor, err := j.MergeDuplicates(DuplicateGroup{
+		UUIDs: []string{"FF755C6D7D9B4A5FBC4E41C07D622C65", "871D0F435D7B469C9429CD441A9E74B5"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if survivor != "FF755C6D7D9B4A5FBC4E41C07D622C65" {
+		t.Errorf("unexpected survivor: %v", survivor)
+	}
+
+	e, err = j.ReadEntry(survivor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(e.Tags, []string{"bjj", "fitness", "judo"}) {
+		t.Errorf("unexpected tags: %v", e.Tags)
+	}
+
+	if _, err := j.PhotoStat(survivor); err != nil {
+		t.Error("expected photo to move to survivor")
+	}
+
+	if _, err := j.EntryStat("871D0F435D7B469C9429CD441A9E74B5"); err == nil {
+		t.Error("expected duplicate to be deleted")
+	}
+}
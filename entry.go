@@ -5,6 +5,8 @@ import (
 	"github.com/DHowett/go-plist"
 	"github.com/twinj/uuid"
 	"io"
+	"os"
+	"runtime"
 	"strings"
 	"time"
 )
@@ -29,6 +31,11 @@ type Entry struct {
 	TimeZone     string
 	Creator      *Creator
 	CreationDate time.Time
+
+	// modTime and size are the stat of the entry file when
+	// the entry was read, used to detect conflicting writes.
+	modTime time.Time
+	size    int64
 }
 
 // Creator is the creator of a journal entry.
@@ -94,23 +101,111 @@ func (e *Entry) UUID() string {
 	return e.uuid
 }
 
-func newEntry() *Entry {
-	id := uuid.NewV4()
+// Title gets the first non-blank line of the entry text,
+// without any leading Markdown heading markers, the way
+// Day One shows it in the entry list.
+func (e *Entry) Title() string {
+	for _, line := range strings.Split(e.EntryText, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+		if line != "" {
+			return line
+		}
+	}
+	return ""
+}
 
+// LocalCreationDate gets the CreationDate in the entry's
+// TimeZone, or as stored if the time zone is missing or unknown.
+func (e *Entry) LocalCreationDate() time.Time {
+	if e.TimeZone != "" {
+		if loc, err := time.LoadLocation(e.TimeZone); err == nil {
+			return e.CreationDate.In(loc)
+		}
+	}
+	return e.CreationDate
+}
+
+func newEntry() *Entry {
 	return &Entry{
-		uuid: strings.ToUpper(uuid.Formatter(id, uuid.Clean)), // e.g. FF755C6D7D9B4A5FBC4E41C07D622C65
+		uuid:         newUUID(),
+		CreationDate: time.Now().UTC(),
 	}
 }
 
-func (e *Entry) validate() error {
-	if e.uuid == "" {
-		return errors.New("missing uuid")
+func newUUID() string {
+	id := uuid.NewV4()
+	return strings.ToUpper(uuid.Formatter(id, uuid.Clean)) // e.g. FF755C6D7D9B4A5FBC4E41C07D622C65
+}
+
+func (e *Entry) setStat(fi os.FileInfo) {
+	e.modTime = fi.ModTime()
+	e.size = fi.Size()
+}
+
+// forgetStat detaches the entry from the file it was read
+// from so it can be written to another journal.
+func (e *Entry) forgetStat() {
+	e.modTime = time.Time{}
+	e.size = 0
+}
+
+// clone returns a deep copy of the entry.
+func (e *Entry) clone() *Entry {
+	c := *e
+
+	if e.Tags != nil {
+		c.Tags = append([]string(nil), e.Tags...)
+	}
+	if e.Creator != nil {
+		creator := *e.Creator
+		c.Creator = &creator
+	}
+	if e.Weather != nil {
+		weather := *e.Weather
+		c.Weather = &weather
+	}
+	if e.Music != nil {
+		music := *e.Music
+		c.Music = &music
+	}
+	if e.Location != nil {
+		loc := *e.Location
+		if loc.Region != nil {
+			region := *loc.Region
+			if region.Center != nil {
+				center := *region.Center
+				region.Center = &center
+			}
+			loc.Region = &region
+		}
+		c.Location = &loc
 	}
 
-	return nil
+	return &c
+}
+
+// UnknownKeyError is returned when an entry file contains
+// a key this package doesn't know how to read.
+type UnknownKeyError struct {
+	Key string
+}
+
+func (e *UnknownKeyError) Error() string {
+	return "unexpected key: " + e.Key
 }
 
-func (e *Entry) parse(r io.ReadSeeker) error {
+func (e *Entry) parse(r io.ReadSeeker) (err error) {
+	defer func() {
+		// A value of the wrong type for its key.
+		if r := recover(); r != nil {
+			if te, ok := r.(*runtime.TypeAssertionError); ok {
+				err = errors.New("invalid value: " + te.Error())
+				return
+			}
+			panic(r)
+		}
+	}()
+
 	dec := plist.NewDecoder(r)
 
 	var dict map[string]interface{}
@@ -168,13 +263,60 @@ func (e *Entry) parse(r io.ReadSeeker) error {
 				return err
 			}
 		default:
-			return errors.New("unexpected key: " + k)
+			return &UnknownKeyError{Key: k}
 		}
 	}
 
 	return nil
 }
 
+func (e *Entry) encode(w io.Writer) error {
+	enc := plist.NewEncoder(w)
+	enc.Indent("\t")
+	return enc.Encode(e.dict())
+}
+
+func (e *Entry) dict() map[string]interface{} {
+	dict := map[string]interface{}{
+		"UUID":          e.uuid,
+		"Entry Text":    e.EntryText,
+		"Creation Date": e.CreationDate,
+		"Starred":       e.Starred,
+	}
+	if e.Activity != "" {
+		dict["Activity"] = e.Activity
+	}
+	if e.TimeZone != "" {
+		dict["Time Zone"] = e.TimeZone
+	}
+	if e.IgnoreStepCount {
+		dict["Ignore Step Count"] = e.IgnoreStepCount
+	}
+	if e.StepCount != 0 {
+		dict["Step Count"] = e.StepCount
+	}
+	if len(e.Tags) > 0 {
+		dict["Tags"] = e.Tags
+	}
+	if e.Creator != nil {
+		dict["Creator"] = e.Creator.dict()
+	}
+	if e.Location != nil {
+		dict["Location"] = e.Location.dict()
+	}
+	if e.Weather != nil {
+		dict["Weather"] = e.Weather.dict()
+	}
+	if e.PublishURL != "" {
+		dict["Publish URL"] = e.PublishURL
+	}
+	if e.Music != nil {
+		dict["Music"] = e.Music.dict()
+	}
+
+	return dict
+}
+
 func parseStringArray(in []interface{}) ([]string, error) {
 	var out []string
 	for _, v := range in {
@@ -198,7 +340,7 @@ func (c *Creator) parse(dict map[string]interface{}) error {
 		case "Software Agent":
 			c.SoftwareAgent = v.(string)
 		default:
-			return errors.New("unexpected key: " + k)
+			return &UnknownKeyError{Key: k}
 		}
 	}
 
@@ -229,7 +371,7 @@ func (l *Location) parse(dict map[string]interface{}) error {
 				return err
 			}
 		default:
-			return errors.New("unexpected key: " + k)
+			return &UnknownKeyError{Key: k}
 		}
 	}
 	return nil
@@ -247,7 +389,7 @@ func (r *Region) parse(dict map[string]interface{}) error {
 				return err
 			}
 		default:
-			return errors.New("unexpected key: " + k)
+			return &UnknownKeyError{Key: k}
 		}
 	}
 	return nil
@@ -261,7 +403,7 @@ func (c *Coordinate) parse(dict map[string]interface{}) error {
 		case "Longitude":
 			c.Longitude = v.(float64)
 		default:
-			return errors.New("unexpected key: " + k)
+			return &UnknownKeyError{Key: k}
 		}
 	}
 	return nil
@@ -322,7 +464,7 @@ func (w *Weather) parse(dict map[string]interface{}) error {
 				w.WindSpeedKPH = v.(float64)
 			}
 		default:
-			return errors.New("unexpected key: " + k)
+			return &UnknownKeyError{Key: k}
 		}
 	}
 	return nil
@@ -340,8 +482,129 @@ func (m *Music) parse(dict map[string]interface{}) error {
 		case "Album Year":
 			m.AlbumYear = v.(string)
 		default:
-			return errors.New("unexpected key: " + k)
+			return &UnknownKeyError{Key: k}
 		}
 	}
 	return nil
 }
+
+func (c *Creator) dict() map[string]interface{} {
+	dict := map[string]interface{}{}
+	if c.DeviceAgent != "" {
+		dict["Device Agent"] = c.DeviceAgent
+	}
+	if !c.GenerationDate.IsZero() {
+		dict["Generation Date"] = c.GenerationDate
+	}
+	if c.HostName != "" {
+		dict["Host Name"] = c.HostName
+	}
+	if c.OSAgent != "" {
+		dict["OS Agent"] = c.OSAgent
+	}
+	if c.SoftwareAgent != "" {
+		dict["Software Agent"] = c.SoftwareAgent
+	}
+	return dict
+}
+
+func (l *Location) dict() map[string]interface{} {
+	dict := l.Coordinate.dict()
+	if l.AdministrativeArea != "" {
+		dict["Administrative Area"] = l.AdministrativeArea
+	}
+	if l.Country != "" {
+		dict["Country"] = l.Country
+	}
+	if l.Locality != "" {
+		dict["Locality"] = l.Locality
+	}
+	if l.PlaceName != "" {
+		dict["Place Name"] = l.PlaceName
+	}
+	if l.FoursquareID != "" {
+		dict["Foursquare ID"] = l.FoursquareID
+	}
+	if l.Region != nil {
+		dict["Region"] = l.Region.dict()
+	}
+	return dict
+}
+
+func (r *Region) dict() map[string]interface{} {
+	dict := map[string]interface{}{
+		"Radius": r.Radius,
+	}
+	if r.Center != nil {
+		dict["Center"] = r.Center.dict()
+	}
+	return dict
+}
+
+func (c *Coordinate) dict() map[string]interface{} {
+	return map[string]interface{}{
+		"Latitude":  c.Latitude,
+		"Longitude": c.Longitude,
+	}
+}
+
+func (w *Weather) dict() map[string]interface{} {
+	dict := map[string]interface{}{}
+	if w.Celsius != "" {
+		dict["Celsius"] = w.Celsius
+	}
+	if w.Fahrenheit != "" {
+		dict["Fahrenheit"] = w.Fahrenheit
+	}
+	if w.Description != "" {
+		dict["Description"] = w.Description
+	}
+	if w.IconName != "" {
+		dict["IconName"] = w.IconName
+	}
+	if w.Service != "" {
+		dict["Service"] = w.Service
+	}
+	if w.PressureMB != 0 {
+		dict["Pressure MB"] = w.PressureMB
+	}
+	if w.RelativeHumidity != 0 {
+		dict["Relative Humidity"] = w.RelativeHumidity
+	}
+	if w.VisibilityKM != 0 {
+		dict["Visibility KM"] = w.VisibilityKM
+	}
+	if w.WindBearing != 0 {
+		dict["Wind Bearing"] = w.WindBearing
+	}
+	if w.WindChillCelsius != 0 {
+		dict["Wind Chill Celsius"] = w.WindChillCelsius
+	}
+	if w.WindSpeedKPH != 0 {
+		dict["Wind Speed KPH"] = w.WindSpeedKPH
+	}
+	if !w.SunriseDate.IsZero() {
+		dict["Sunrise Date"] = w.SunriseDate
+	}
+	if !w.SunsetDate.IsZero() {
+		dict["Sunset Date"] = w.SunsetDate
+	}
+	return dict
+}
+
+func (m *Music) dict() map[string]interface{} {
+	dict := map[string]interface{}{}
+	if m.Album != "" {
+		dict["Album"] = m.Album
+	}
+	if m.Artist != "" {
+		dict["Artist"] = m.Artist
+	}
+	if m.Track != "" {
+		dict["Track"] = m.Track
+	}
+	if m.AlbumYear != "" {
+		dict["Album Year"] = m.AlbumYear
+	}
+	return dict
+}
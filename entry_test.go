@@ -2,6 +2,8 @@ package dayone
 
 import (
 	"bytes"
+	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -23,11 +25,38 @@ func TestNewEntryHasUUID(t *testing.T) {
 	}
 }
 
+func TestTitle(t *testing.T) {
+	e := &Entry{EntryText: "\n\n## My day \nbody"}
+	if e.Title() != "My day" {
+		t.Errorf("unexpected title %q", e.Title())
+	}
+
+	e.EntryText = ""
+	if e.Title() != "" {
+		t.Error("expected empty title")
+	}
+}
+
+func TestLocalCreationDate(t *testing.T) {
+	e := &Entry{
+		CreationDate: time.Date(2014, 9, 24, 1, 52, 11, 0, time.UTC),
+		TimeZone:     "America/Chicago",
+	}
+	if e.LocalCreationDate().Format("2006-01-02 15:04") != "2014-09-23 20:52" {
+		t.Errorf("unexpected local date %v", e.LocalCreationDate())
+	}
+
+	e.TimeZone = "Nowhere/Special"
+	if !e.LocalCreationDate().Equal(e.CreationDate) {
+		t.Error("expected creation date for unknown time zone")
+	}
+}
+
 func TestValidateMissingUUID(t *testing.T) {
 	e := &Entry{}
 
-	err := e.validate()
-	if err == nil || err.Error() != "missing uuid" {
+	err, ok := e.validate().(*ValidationError)
+	if !ok || err.Problems[0].Field != "UUID" || err.Problems[0].Message != "missing uuid" {
 		t.Fail()
 	}
 }
@@ -347,3 +376,30 @@ body line` {
 		}
 	}
 }
+
+func TestEncodingEntryRoundTrips(t *testing.T) {
+	f, err := os.Open("./test_journals/default/entries/FF755C6D7D9B4A5FBC4E41C07D622C65.doentry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	e := &Entry{}
+	if err := e.parse(f); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := e.encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var e2 Entry
+	if err := e2.parse(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(e, &e2) {
+		t.Errorf("entry changed after round trip:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,69 @@
+package dayone
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// exportEntries reads the entries matching filter, oldest
+// first. A nil filter matches every entry. Entries that can't
+// be read stop the export.
+func (j *Journal) exportEntries(filter FilterFunc) ([]*Entry, error) {
+	var entries []*Entry
+
+	err := j.Read(func(e *Entry, err error) error {
+		if e == nil {
+			return err
+		}
+		if filter == nil || filter(e) {
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(a, b int) bool {
+		return entries[a].CreationDate.Before(entries[b].CreationDate)
+	})
+	return entries, nil
+}
+
+// exportPath returns the path of e's file in an export,
+// without an extension, e.g.
+//
+//	2014/09/2014-09-23-FF755C6D7D9B4A5FBC4E41C07D622C65
+func exportPath(e *Entry) string {
+	local := e.LocalCreationDate()
+	return filepath.Join(
+		local.Format("2006"),
+		local.Format("01"),
+		local.Format("2006-01-02")+"-"+e.UUID(),
+	)
+}
+
+// excerpt returns the text after the title line with its
+// whitespace collapsed, cut at a word boundary to at most
+// n runes with "…" appended if it was cut.
+func excerpt(text string, n int) string {
+	lines := strings.SplitN(strings.TrimSpace(text), "\n", 2)
+	if len(lines) < 2 {
+		return ""
+	}
+
+	words := strings.Fields(lines[1])
+	var out []string
+	length := 0
+	for _, w := range words {
+		wl := utf8.RuneCountInString(w)
+		if length+wl > n {
+			return strings.Join(out, " ") + "…"
+		}
+		out = append(out, w)
+		length += wl + 1
+	}
+	return strings.Join(out, " ")
+}
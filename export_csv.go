@@ -0,0 +1,166 @@
+package dayone
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Extra CSV columns that aren't Entry fields.
+const (
+	CSVTitle   = "Title"
+	CSVExcerpt = "Excerpt"
+)
+
+// CSVColumns returns the name of every column ExportCSV can
+// write: each Entry field flattened with dotted names as in
+// DiffEntries (e.g. "Location.Country"), then CSVTitle and
+// CSVExcerpt.
+func CSVColumns() []string {
+	return append(append([]string(nil), entryFieldNames...), CSVTitle, CSVExcerpt)
+}
+
+// CSVOptions configures Journal.ExportCSV.
+// The zero value uses the defaults.
+type CSVOptions struct {
+	// Columns are the columns to write, in order, named as
+	// in CSVColumns. Defaults to every Entry field, without
+	// the title and excerpt.
+	Columns []string
+
+	// Filter selects the entries to export.
+	// nil exports every entry.
+	Filter FilterFunc
+
+	// TagSeparator joins the tags in the Tags column.
+	// Defaults to ", ".
+	TagSeparator string
+
+	// TagPrefix is written before each tag, e.g. "#".
+	TagPrefix string
+
+	// UTC writes dates in UTC rather than in each
+	// entry's TimeZone.
+	UTC bool
+
+	// ExcerptLength is the most characters of text in the
+	// Excerpt column. Defaults to 200.
+	ExcerptLength int
+
+	// NoHeader leaves out the header row.
+	NoHeader bool
+}
+
+// entryTimeFields are the flattened names of the
+// Entry fields holding times.
+var entryTimeFields = timeFieldNames("", reflect.TypeOf(Entry{}), make(map[string]bool))
+
+// timeFieldNames adds the names of the time fields in the
+// struct type t to names, named the same way as flattenValue.
+func timeFieldNames(prefix string, t reflect.Type, names map[string]bool) map[string]bool {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		ft := sf.Type
+		switch {
+		case sf.PkgPath != "":
+		case ft == reflect.TypeOf(time.Time{}):
+			names[prefix+sf.Name] = true
+		case sf.Anonymous:
+			timeFieldNames(prefix, ft, names)
+		case ft.Kind() == reflect.Ptr && ft.Elem().Kind() == reflect.Struct:
+			timeFieldNames(prefix+sf.Name+".", ft.Elem(), names)
+		}
+	}
+	return names
+}
+
+// ExportCSV writes the entries matching opts.Filter to w as
+// CSV, one row per entry in the order they are read. Entries
+// are written as they are read rather than all at once.
+// opts may be nil. It returns the number of entries written.
+func (j *Journal) ExportCSV(w io.Writer, opts *CSVOptions) (int, error) {
+	var o CSVOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Columns == nil {
+		o.Columns = entryFieldNames
+	}
+	if o.TagSeparator == "" {
+		o.TagSeparator = ", "
+	}
+	if o.ExcerptLength <= 0 {
+		o.ExcerptLength = 200
+	}
+
+	valid := CSVColumns()
+	for _, c := range o.Columns {
+		if !containsTag(valid, c) {
+			return 0, errors.New("unknown CSV column " + c)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if !o.NoHeader {
+		if err := cw.Write(o.Columns); err != nil {
+			return 0, err
+		}
+	}
+
+	n := 0
+	row := make([]string, len(o.Columns))
+	err := j.Read(func(e *Entry, err error) error {
+		if e == nil {
+			return err
+		}
+		if o.Filter != nil && !o.Filter(e) {
+			return nil
+		}
+
+		fields := flattenEntry(e)
+		for i, c := range o.Columns {
+			row[i] = o.value(e, c, fields[c])
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	if err != nil {
+		return n, err
+	}
+
+	cw.Flush()
+	return n, cw.Error()
+}
+
+// value returns the value of column c for e, where
+// f is the field from flattenEntry.
+func (o *CSVOptions) value(e *Entry, c string, f field) string {
+	switch {
+	case c == CSVTitle:
+		return e.Title()
+	case c == CSVExcerpt:
+		return excerpt(e.EntryText, o.ExcerptLength)
+	case c == "Tags":
+		tags := make([]string, len(e.Tags))
+		for i, tag := range e.Tags {
+			tags[i] = o.TagPrefix + tag
+		}
+		return strings.Join(tags, o.TagSeparator)
+	case entryTimeFields[c] && f.raw.IsValid():
+		t := f.raw.Interface().(time.Time)
+		if t.IsZero() {
+			return ""
+		}
+		if o.UTC {
+			return t.UTC().Format(time.RFC3339)
+		}
+		return t.In(e.LocalCreationDate().Location()).Format(time.RFC3339)
+	}
+	return f.value
+}
@@ -0,0 +1,96 @@
+package dayone
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+)
+
+func readCSV(t *testing.T, j *Journal, opts *CSVOptions) [][]string {
+	var buf bytes.Buffer
+	if _, err := j.ExportCSV(&buf, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestExportCSVDefaults(t *testing.T) {
+	rows := readCSV(t, NewJournal("./test_journals/default"), nil)
+
+	if len(rows) != 3 {
+		t.Fatalf("expected header and 2 rows, got %d", len(rows))
+	}
+	if !reflect.DeepEqual(rows[0], entryFieldNames) {
+		t.Errorf("unexpected header: %v", rows[0])
+	}
+
+	row := make(map[string]string)
+	for i, c := range rows[0] {
+		row[c] = rows[2][i]
+	}
+	for c, expected := range map[string]string{
+		"UUID":                "FF755C6D7D9B4A5FBC4E41C07D622C65",
+		"CreationDate":        "2014-09-23T20:52:11-05:00",
+		"Tags":                "bjj, fitness",
+		"Location.Country":    "United States",
+		"Weather.SunriseDate": "2014-09-23T07:20:49-05:00",
+		"Creator.HostName":    "Joshua's iPhone",
+		"Music.Artist":        "",
+	} {
+		if row[c] != expected {
+			t.Errorf("expected %s to be %q, got %q", c, expected, row[c])
+		}
+	}
+}
+
+func TestExportCSVOptions(t *testing.T) {
+	rows := readCSV(t, NewJournal("./test_journals/default"), &CSVOptions{
+		Columns:       []string{"UUID", "CreationDate", "Tags", CSVTitle, CSVExcerpt},
+		Filter:        func(e *Entry) bool { return e.UUID() == "FF755C6D7D9B4A5FBC4E41C07D622C65" },
+		TagSeparator:  ";",
+		TagPrefix:     "#",
+		UTC:           true,
+		ExcerptLength: 4,
+		NoHeader:      true,
+	})
+
+	expected := [][]string{{
+		"FF755C6D7D9B4A5FBC4E41C07D622C65",
+		"2014-09-24T01:52:11Z",
+		"#bjj;#fitness",
+		"title line",
+		"body…",
+	}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("unexpected rows: %v", rows)
+	}
+}
+
+func TestExportCSVUnknownColumn(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := NewJournal("./test_journals/default").ExportCSV(&buf, &CSVOptions{Columns: []string{"Mood"}})
+	if err == nil {
+		t.Error("expected error")
+	}
+	if buf.Len() != 0 {
+		t.Error("expected nothing written")
+	}
+}
+
+func TestEntryTimeFields(t *testing.T) {
+	expected := map[string]bool{
+		"CreationDate":           true,
+		"Creator.GenerationDate": true,
+		"Weather.SunriseDate":    true,
+		"Weather.SunsetDate":     true,
+	}
+	if !reflect.DeepEqual(entryTimeFields, expected) {
+		t.Errorf("unexpected time fields: %v", entryTimeFields)
+	}
+}
@@ -0,0 +1,365 @@
+package dayone
+
+import (
+	"archive/zip"
+	"github.com/juju/errgo"
+	"hash/crc32"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// EPUBOptions configures Journal.ExportEPUB.
+// The zero value uses the defaults.
+type EPUBOptions struct {
+	// Title is the title of the book.
+	// Defaults to "Day One".
+	Title string
+
+	// Author is the book's author, if any.
+	Author string
+
+	// Language is the book's language tag.
+	// Defaults to "en".
+	Language string
+
+	// Identifier is the book's unique identifier.
+	// Defaults to a new urn:uuid.
+	Identifier string
+
+	// Since and Until limit the book to entries created
+	// at or after Since and before Until. Zero times
+	// don't limit it.
+	Since, Until time.Time
+
+	// Filter selects the entries to include.
+	// nil includes every entry.
+	Filter FilterFunc
+}
+
+const epubMimetype = "application/epub+zip"
+
+type epubBook struct {
+	Title      string
+	Author     string
+	Language   string
+	Identifier string
+	Modified   string
+	First      string
+	Last       string
+	Chapters   []*epubChapter
+	Photos     []string // entry uuids
+}
+
+// epubChapter is a month of entries.
+type epubChapter struct {
+	ID      string
+	Name    string
+	Entries []*epubEntry
+}
+
+type epubEntry struct {
+	ID         string
+	Chapter    *epubChapter
+	Title      string
+	Date       string
+	Photo      string
+	Body       template.HTML
+	Tags       []string
+	Starred    bool
+	Place      string
+	Coordinate string
+	Weather    string
+}
+
+// ExportEPUB writes the entries matching opts to w as an
+// EPUB 3 book, oldest first, with a chapter for each month
+// and each entry's photo shown above its text. The book
+// starts with a title page and table of contents and ends
+// with a page listing each entry's location and weather.
+// opts may be nil. It returns the number of entries written.
+func (j *Journal) ExportEPUB(w io.Writer, opts *EPUBOptions) (int, error) {
+	var o EPUBOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Title == "" {
+		o.Title = "Day One"
+	}
+	if o.Language == "" {
+		o.Language = "en"
+	}
+	if o.Identifier == "" {
+		o.Identifier = uuidURN(newUUID())
+	}
+
+	entries, err := j.exportEntries(func(e *Entry) bool {
+		if !o.Since.IsZero() && e.CreationDate.Before(o.Since) {
+			return false
+		}
+		if !o.Until.IsZero() && !e.CreationDate.Before(o.Until) {
+			return false
+		}
+		return o.Filter == nil || o.Filter(e)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	book := &epubBook{
+		Title:      o.Title,
+		Author:     o.Author,
+		Language:   o.Language,
+		Identifier: o.Identifier,
+		Modified:   time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+	}
+	for _, e := range entries {
+		if err := book.addEntry(j, e); err != nil {
+			return 0, err
+		}
+	}
+	if len(entries) > 0 {
+		book.First = entries[0].LocalCreationDate().Format("January 2, 2006")
+		book.Last = entries[len(entries)-1].LocalCreationDate().Format("January 2, 2006")
+	}
+
+	if err := j.writeEPUB(w, book); err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+func (b *epubBook) addEntry(j *Journal, e *Entry) error {
+	local := e.LocalCreationDate()
+
+	id := "chapter-" + local.Format("2006-01")
+	var c *epubChapter
+	if n := len(b.Chapters); n > 0 && b.Chapters[n-1].ID == id {
+		c = b.Chapters[n-1]
+	} else {
+		c = &epubChapter{ID: id, Name: local.Format("January 2006")}
+		b.Chapters = append(b.Chapters, c)
+	}
+
+	ee := &epubEntry{
+		ID:      "entry-" + e.UUID(),
+		Chapter: c,
+		Title:   placeName(e),
+		Date:    local.Format("Monday, January 2, 2006 3:04 PM"),
+		Body:    template.HTML(renderMarkdown(e.EntryText)),
+		Tags:    e.Tags,
+		Starred: e.Starred,
+	}
+	if l := e.Location; l != nil {
+		ee.Place = joinNonEmpty(", ", l.PlaceName, l.Locality, l.AdministrativeArea, l.Country)
+		ee.Coordinate = formatFloat(l.Latitude) + ", " + formatFloat(l.Longitude)
+	}
+	if w := e.Weather; w != nil {
+		ee.Weather = joinNonEmpty(", ", w.Description, degrees(w.Celsius, "C"), degrees(w.Fahrenheit, "F"))
+	}
+
+	_, err := os.Stat(filepath.Join(j.getPhotosDir(), e.UUID()+photoExt))
+	if err == nil {
+		ee.Photo = "images/" + e.UUID() + photoExt
+		b.Photos = append(b.Photos, e.UUID())
+	} else if !os.IsNotExist(err) {
+		return errgo.Mask(err)
+	}
+
+	c.Entries = append(c.Entries, ee)
+	return nil
+}
+
+func (j *Journal) writeEPUB(w io.Writer, book *epubBook) error {
+	zw := zip.NewWriter(w)
+
+	// The mimetype must come first, stored uncompressed
+	// without a data descriptor so readers can sniff it.
+	f, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE([]byte(epubMimetype)),
+		CompressedSize64:   uint64(len(epubMimetype)),
+		UncompressedSize64: uint64(len(epubMimetype)),
+	})
+	if err != nil {
+		return errgo.Mask(err)
+	}
+	if _, err := io.WriteString(f, epubMimetype); err != nil {
+		return errgo.Mask(err)
+	}
+
+	pages := []struct {
+		name, template string
+		data           interface{}
+	}{
+		{"META-INF/container.xml", "container", nil},
+		{"OEBPS/content.opf", "package", book},
+		{"OEBPS/nav.xhtml", "nav", book},
+		{"OEBPS/title.xhtml", "title", book},
+		{"OEBPS/metadata.xhtml", "metadata", book},
+	}
+	for _, p := range pages {
+		if err := writeEPUBTemplate(zw, p.name, p.template, p.data); err != nil {
+			return err
+		}
+	}
+	for _, c := range book.Chapters {
+		if err := writeEPUBTemplate(zw, "OEBPS/"+c.ID+".xhtml", "chapter", c); err != nil {
+			return err
+		}
+	}
+
+	f, err = zw.Create("OEBPS/style.css")
+	if err != nil {
+		return errgo.Mask(err)
+	}
+	if _, err := io.WriteString(f, epubStyle); err != nil {
+		return errgo.Mask(err)
+	}
+
+	for _, uuid := range book.Photos {
+		if err := j.writeEPUBPhoto(zw, uuid); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return errgo.Mask(err)
+	}
+	return nil
+}
+
+func writeEPUBTemplate(zw *zip.Writer, name, template string, data interface{}) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return errgo.Mask(err)
+	}
+	if _, err := io.WriteString(f, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"); err != nil {
+		return errgo.Mask(err)
+	}
+	return epubTemplates.ExecuteTemplate(f, template, data)
+}
+
+func (j *Journal) writeEPUBPhoto(zw *zip.Writer, uuid string) error {
+	r, err := j.OpenPhoto(uuid)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// JPEGs are already compressed.
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: "OEBPS/images/" + uuid + photoExt, Method: zip.Store})
+	if err != nil {
+		return errgo.Mask(err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		return errgo.Mask(err)
+	}
+	return nil
+}
+
+var epubTemplates = template.Must(template.New("").Parse(`
+{{define "container"}}<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles>
+<rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+</rootfiles>
+</container>
+{{end}}
+
+{{define "package"}}<package version="3.0" xmlns="http://www.idpf.org/2007/opf" unique-identifier="book-id">
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:identifier id="book-id">{{.Identifier}}</dc:identifier>
+<dc:title>{{.Title}}</dc:title>
+<dc:language>{{.Language}}</dc:language>
+{{with .Author}}<dc:creator>{{.}}</dc:creator>
+{{end}}<meta property="dcterms:modified">{{.Modified}}</meta>
+</metadata>
+<manifest>
+<item id="title" href="title.xhtml" media-type="application/xhtml+xml"/>
+<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+{{range .Chapters}}<item id="{{.ID}}" href="{{.ID}}.xhtml" media-type="application/xhtml+xml"/>
+{{end}}<item id="metadata" href="metadata.xhtml" media-type="application/xhtml+xml"/>
+<item id="style" href="style.css" media-type="text/css"/>
+{{range .Photos}}<item id="photo-{{.}}" href="images/{{.}}.jpg" media-type="image/jpeg"/>
+{{end}}</manifest>
+<spine>
+<itemref idref="title"/>
+<itemref idref="nav"/>
+{{range .Chapters}}<itemref idref="{{.ID}}"/>
+{{end}}<itemref idref="metadata"/>
+</spine>
+</package>
+{{end}}
+
+{{define "header"}}<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head>
+<meta charset="utf-8"/>
+<title>{{.}}</title>
+<link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+{{end}}
+
+{{define "footer"}}</body>
+</html>
+{{end}}
+
+{{define "title"}}{{template "header" .Title}}<section epub:type="titlepage" class="titlepage">
+<h1>{{.Title}}</h1>
+{{with .Author}}<p class="author">{{.}}</p>
+{{end}}{{if .First}}<p class="dates">{{.First}}{{if ne .First .Last}} – {{.Last}}{{end}}</p>
+{{end}}</section>
+{{template "footer"}}{{end}}
+
+{{define "nav"}}{{template "header" "Contents"}}<nav epub:type="toc" id="toc">
+<h1>Contents</h1>
+<ol>
+{{range .Chapters}}<li><a href="{{.ID}}.xhtml">{{.Name}}</a>
+<ol>
+{{range .Entries}}<li><a href="{{.Chapter.ID}}.xhtml#{{.ID}}">{{.Title}}</a></li>
+{{end}}</ol>
+</li>
+{{end}}<li><a href="metadata.xhtml">Places and weather</a></li>
+</ol>
+</nav>
+{{template "footer"}}{{end}}
+
+{{define "chapter"}}{{template "header" .Name}}<section epub:type="chapter">
+<h1>{{.Name}}</h1>
+{{range .Entries}}<article id="{{.ID}}">
+<p class="date">{{.Date}}{{if .Starred}} ★{{end}}</p>
+{{with .Photo}}<img class="photo" src="{{.}}" alt=""/>
+{{end}}{{.Body}}{{with .Tags}}<p class="tags">{{range .}}#{{.}} {{end}}</p>
+{{end}}</article>
+{{end}}</section>
+{{template "footer"}}{{end}}
+
+{{define "metadata"}}{{template "header" "Places and weather"}}<section epub:type="appendix">
+<h1>Places and weather</h1>
+<table>
+<thead><tr><th>Entry</th><th>Location</th><th>Weather</th></tr></thead>
+<tbody>
+{{range .Chapters}}{{range .Entries}}<tr>
+<td><a href="{{.Chapter.ID}}.xhtml#{{.ID}}">{{.Title}}</a><br/>{{.Date}}</td>
+<td>{{.Place}}{{with .Coordinate}}<br/>{{.}}{{end}}</td>
+<td>{{.Weather}}</td>
+</tr>
+{{end}}{{end}}</tbody>
+</table>
+</section>
+{{template "footer"}}{{end}}
+`))
+
+const epubStyle = `body { font-family: serif; line-height: 1.4; }
+h1 { text-align: center; }
+.titlepage { margin-top: 30%; text-align: center; }
+.date, .tags, .author, .dates { color: #666; }
+article { margin-bottom: 2em; }
+img.photo { max-width: 100%; }
+table { border-collapse: collapse; font-size: 0.9em; }
+td, th { border-bottom: 1px solid #ccc; padding: 0.3em; text-align: left; vertical-align: top; }
+`
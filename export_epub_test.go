@@ -0,0 +1,133 @@
+package dayone
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readEPUB(t *testing.T, opts *EPUBOptions) (int, *zip.Reader) {
+	var buf bytes.Buffer
+	n, err := NewJournal("./test_journals/default").ExportEPUB(&buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n, zr
+}
+
+func readZipFile(t *testing.T, zr *zip.Reader, name string) string {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	t.Fatalf("missing %s", name)
+	return ""
+}
+
+func TestExportEPUB(t *testing.T) {
+	n, zr := readEPUB(t, &EPUBOptions{Title: "Training <log>", Author: "Joshua"})
+	if n != 2 {
+		t.Errorf("expected 2 entries, got %d", n)
+	}
+
+	first := zr.File[0]
+	if first.Name != "mimetype" || first.Method != zip.Store || first.Flags&0x8 != 0 {
+		t.Errorf("expected stored mimetype first, got %+v", first.FileHeader)
+	}
+	if s := readZipFile(t, zr, "mimetype"); s != "application/epub+zip" {
+		t.Errorf("unexpected mimetype: %s", s)
+	}
+
+	// Every document must be well-formed XML.
+	for _, f := range zr.File {
+		if !strings.HasSuffix(f.Name, ".xhtml") && !strings.HasSuffix(f.Name, ".opf") && !strings.HasSuffix(f.Name, ".xml") {
+			continue
+		}
+		d := xml.NewDecoder(strings.NewReader(readZipFile(t, zr, f.Name)))
+		for {
+			_, err := d.Token()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Errorf("%s: %v", f.Name, err)
+				break
+			}
+		}
+	}
+
+	opf := readZipFile(t, zr, "OEBPS/content.opf")
+	for _, expected := range []string{
+		"<dc:title>Training &lt;log&gt;</dc:title>",
+		"<dc:creator>Joshua</dc:creator>",
+		`<dc:identifier id="book-id">urn:uuid:`,
+		`href="images/871D0F435D7B469C9429CD441A9E74B5.jpg" media-type="image/jpeg"`,
+		`<itemref idref="chapter-2014-09"/>`,
+	} {
+		if !strings.Contains(opf, expected) {
+			t.Errorf("expected %q in package:\n%s", expected, opf)
+		}
+	}
+
+	nav := readZipFile(t, zr, "OEBPS/nav.xhtml")
+	if !strings.Contains(nav, `<a href="chapter-2014-09.xhtml#entry-FF755C6D7D9B4A5FBC4E41C07D622C65">title line</a>`) {
+		t.Errorf("expected entry in table of contents:\n%s", nav)
+	}
+
+	chapter := readZipFile(t, zr, "OEBPS/chapter-2014-09.xhtml")
+	for _, expected := range []string{
+		`<h1>September 2014</h1>`,
+		`<article id="entry-FF755C6D7D9B4A5FBC4E41C07D622C65">`,
+		`<img class="photo" src="images/871D0F435D7B469C9429CD441A9E74B5.jpg" alt=""/>`,
+		`<p>body line</p>`,
+		`#bjj #fitness`,
+	} {
+		if !strings.Contains(chapter, expected) {
+			t.Errorf("expected %q in chapter:\n%s", expected, chapter)
+		}
+	}
+
+	metadata := readZipFile(t, zr, "OEBPS/metadata.xhtml")
+	if !strings.Contains(metadata, "199 Address Ln") || !strings.Contains(metadata, "24°C") {
+		t.Errorf("expected location and weather:\n%s", metadata)
+	}
+
+	if readZipFile(t, zr, "OEBPS/images/871D0F435D7B469C9429CD441A9E74B5.jpg") == "" {
+		t.Error("expected photo")
+	}
+}
+
+func TestExportEPUBDateRange(t *testing.T) {
+	n, zr := readEPUB(t, &EPUBOptions{Since: time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if n != 0 {
+		t.Errorf("expected no entries, got %d", n)
+	}
+	if opf := readZipFile(t, zr, "OEBPS/content.opf"); strings.Contains(opf, "chapter-") {
+		t.Errorf("expected no chapters:\n%s", opf)
+	}
+
+	n, _ = readEPUB(t, &EPUBOptions{Until: time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if n != 2 {
+		t.Errorf("expected 2 entries, got %d", n)
+	}
+}
@@ -0,0 +1,270 @@
+package dayone
+
+import (
+	"encoding/xml"
+	"errors"
+	"github.com/juju/errgo"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FeedOptions configures Journal.ExportAtom and
+// Journal.ExportRSS.
+type FeedOptions struct {
+	// Link is the URL of the site the entries are published
+	// on, e.g. where an ExportHTML site is served. Entries
+	// without a PublishURL link to their page on the site,
+	// and photos are linked as enclosures on the site. It
+	// is required.
+	Link string
+
+	// Title is the title of the feed.
+	// Defaults to "Day One".
+	Title string
+
+	// Author is the name of the feed's author.
+	// Defaults to the title.
+	Author string
+
+	// Filter selects the entries to publish, e.g. the
+	// starred entries or those with a tag.
+	// nil publishes every entry.
+	Filter FilterFunc
+
+	// Limit is the most entries in the feed, newest
+	// first. Zero means no limit.
+	Limit int
+}
+
+// ErrNoFeedLink is returned by the feed exporters when
+// FeedOptions.Link is empty.
+var ErrNoFeedLink = errors.New("feed link is required")
+
+// feedItem is an entry ready for a feed.
+type feedItem struct {
+	id      string
+	title   string
+	link    string
+	date    time.Time
+	content string
+	tags    []string
+	photo   *feedEnclosure
+}
+
+type feedEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// feedItems fills in the defaults in o and returns the
+// feed's entries newest first.
+func (j *Journal) feedItems(o *FeedOptions) ([]*feedItem, error) {
+	if o.Link == "" {
+		return nil, ErrNoFeedLink
+	}
+	if o.Title == "" {
+		o.Title = "Day One"
+	}
+	if o.Author == "" {
+		o.Author = o.Title
+	}
+	o.Link = strings.TrimSuffix(o.Link, "/") + "/"
+
+	entries, err := j.exportEntries(o.Filter)
+	if err != nil {
+		return nil, err
+	}
+	for a, b := 0, len(entries)-1; a < b; a, b = a+1, b-1 {
+		entries[a], entries[b] = entries[b], entries[a]
+	}
+	if o.Limit > 0 && len(entries) > o.Limit {
+		entries = entries[:o.Limit]
+	}
+
+	items := make([]*feedItem, len(entries))
+	for i, e := range entries {
+		base := o.Link + filepath.ToSlash(exportPath(e))
+		item := &feedItem{
+			id:      uuidURN(e.UUID()),
+			title:   placeName(e),
+			link:    e.PublishURL,
+			date:    e.CreationDate,
+			content: renderMarkdown(e.EntryText),
+			tags:    e.Tags,
+		}
+		if item.link == "" {
+			item.link = base + ".html"
+		}
+
+		fi, err := os.Stat(filepath.Join(j.getPhotosDir(), e.UUID()+photoExt))
+		if err == nil {
+			item.photo = &feedEnclosure{base + photoExt, fi.Size(), "image/jpeg"}
+		} else if !os.IsNotExist(err) {
+			return nil, errgo.Mask(err)
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// uuidURN returns the urn:uuid URI for an entry uuid.
+func uuidURN(uuid string) string {
+	u := strings.ToLower(uuid)
+	if len(u) == 32 {
+		u = u[:8] + "-" + u[8:12] + "-" + u[12:16] + "-" + u[16:20] + "-" + u[20:]
+	}
+	return "urn:uuid:" + u
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  string      `xml:"author>name"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel    string `xml:"rel,attr,omitempty"`
+	Href   string `xml:"href,attr"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// ExportAtom writes the entries matching opts.Filter to w as
+// an Atom feed, newest first, with their text rendered from
+// Markdown to HTML. Entries are identified by urn:uuid IDs
+// made from their uuids, and their photos are linked as
+// enclosures. It returns ErrNoFeedLink if opts is nil or
+// has no Link, and otherwise the number of entries written.
+func (j *Journal) ExportAtom(w io.Writer, opts *FeedOptions) (int, error) {
+	var o FeedOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	items, err := j.feedItems(&o)
+	if err != nil {
+		return 0, err
+	}
+
+	feed := atomFeed{
+		ID:     o.Link,
+		Title:  o.Title,
+		Author: o.Author,
+		Links:  []atomLink{{Href: o.Link}},
+	}
+	updated := time.Now()
+	if len(items) > 0 {
+		updated = items[0].date
+	}
+	for _, item := range items {
+		date := item.date.UTC().Format(time.RFC3339)
+		entry := atomEntry{
+			ID:        item.id,
+			Title:     item.title,
+			Published: date,
+			Updated:   date,
+			Links:     []atomLink{{Rel: "alternate", Href: item.link}},
+			Content:   atomContent{"html", item.content},
+		}
+		if p := item.photo; p != nil {
+			entry.Links = append(entry.Links, atomLink{"enclosure", p.URL, p.Type, p.Length})
+		}
+		for _, tag := range item.tags {
+			entry.Categories = append(entry.Categories, atomCategory{tag})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+
+	return len(items), writeXML(w, feed)
+}
+
+type rssFeed struct {
+	XMLName     xml.Name  `xml:"rss"`
+	Version     string    `xml:"version,attr"`
+	Title       string    `xml:"channel>title"`
+	Link        string    `xml:"channel>link"`
+	Description string    `xml:"channel>description"`
+	PubDate     string    `xml:"channel>pubDate,omitempty"`
+	Items       []rssItem `xml:"channel>item"`
+}
+
+type rssItem struct {
+	Title       string         `xml:"title"`
+	Link        string         `xml:"link"`
+	GUID        rssGUID        `xml:"guid"`
+	PubDate     string         `xml:"pubDate"`
+	Categories  []string       `xml:"category"`
+	Description string         `xml:"description"`
+	Enclosure   *feedEnclosure `xml:"enclosure"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	ID          string `xml:",chardata"`
+}
+
+// ExportRSS writes the entries matching opts.Filter to w as
+// an RSS 2.0 feed, like ExportAtom.
+// It returns the number of entries written.
+func (j *Journal) ExportRSS(w io.Writer, opts *FeedOptions) (int, error) {
+	var o FeedOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	items, err := j.feedItems(&o)
+	if err != nil {
+		return 0, err
+	}
+
+	feed := rssFeed{
+		Version:     "2.0",
+		Title:       o.Title,
+		Link:        o.Link,
+		Description: o.Title + " by " + o.Author,
+	}
+	if len(items) > 0 {
+		feed.PubDate = items[0].date.UTC().Format(time.RFC1123Z)
+	}
+	for _, item := range items {
+		feed.Items = append(feed.Items, rssItem{
+			Title:       item.title,
+			Link:        item.link,
+			GUID:        rssGUID{"false", item.id},
+			PubDate:     item.date.UTC().Format(time.RFC1123Z),
+			Categories:  item.tags,
+			Description: item.content,
+			Enclosure:   item.photo,
+		})
+	}
+
+	return len(items), writeXML(w, feed)
+}
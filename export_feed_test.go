@@ -0,0 +1,109 @@
+package dayone
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestExportAtom(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := NewJournal("./test_journals/default").ExportAtom(&buf, &FeedOptions{
+		Link:   "https://example.com/journal",
+		Filter: func(e *Entry) bool { return e.Starred },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 entries, got %d", n)
+	}
+
+	var feed atomFeed
+	if err := xml.Unmarshal(buf.Bytes(), &feed); err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "Day One" || feed.Author != "Day One" || feed.ID != "https://example.com/journal/" {
+		t.Errorf("unexpected feed: %+v", feed)
+	}
+	if len(feed.Entries) != 2 || feed.Entries[0].Published < feed.Entries[1].Published {
+		t.Fatal("expected entries newest first")
+	}
+	if feed.Updated != feed.Entries[0].Updated {
+		t.Errorf("expected feed updated at newest entry, got %s", feed.Updated)
+	}
+
+	for _, e := range feed.Entries {
+		if e.Content.Type != "html" || !strings.Contains(e.Content.Body, "<h1>title line</h1>") {
+			t.Errorf("unexpected content: %+v", e.Content)
+		}
+		if len(e.Categories) != 2 || e.Categories[0].Term != "bjj" {
+			t.Errorf("unexpected categories: %v", e.Categories)
+		}
+
+		switch e.ID {
+		case "urn:uuid:871d0f43-5d7b-469c-9429-cd441a9e74b5":
+			if len(e.Links) != 2 || e.Links[1].Rel != "enclosure" || e.Links[1].Length == 0 ||
+				!strings.HasSuffix(e.Links[1].Href, "-871D0F435D7B469C9429CD441A9E74B5.jpg") {
+				t.Errorf("expected photo enclosure, got %+v", e.Links)
+			}
+		case "urn:uuid:ff755c6d-7d9b-4a5f-bc4e-41c07d622c65":
+			if len(e.Links) != 1 || e.Links[0].Href != "https://example.com/journal/2014/09/2014-09-23-FF755C6D7D9B4A5FBC4E41C07D622C65.html" {
+				t.Errorf("unexpected links: %+v", e.Links)
+			}
+		default:
+			t.Errorf("unexpected id: %s", e.ID)
+		}
+	}
+}
+
+func TestExportRSS(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := NewJournal("./test_journals/default").ExportRSS(&buf, &FeedOptions{
+		Link:  "https://example.com/",
+		Title: "Training log",
+		Limit: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 entry, got %d", n)
+	}
+
+	var feed rssFeed
+	if err := xml.Unmarshal(buf.Bytes(), &feed); err != nil {
+		t.Fatal(err)
+	}
+	if feed.Version != "2.0" || feed.Title != "Training log" || len(feed.Items) != 1 {
+		t.Fatalf("unexpected feed: %s", buf.String())
+	}
+
+	item := feed.Items[0]
+	if item.GUID.IsPermaLink != "false" || !strings.HasPrefix(item.GUID.ID, "urn:uuid:") {
+		t.Errorf("unexpected guid: %+v", item.GUID)
+	}
+	if feed.PubDate != item.PubDate {
+		t.Errorf("expected channel pubDate %s, got %s", item.PubDate, feed.PubDate)
+	}
+	if !strings.Contains(buf.String(), "&lt;p&gt;body line&lt;/p&gt;") {
+		t.Error("expected escaped html description")
+	}
+}
+
+func TestExportFeedNeedsLink(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := NewJournal("./test_journals/default").ExportAtom(&buf, nil); err != ErrNoFeedLink {
+		t.Errorf("expected ErrNoFeedLink, got %v", err)
+	}
+	if _, err := NewJournal("./test_journals/default").ExportRSS(&buf, &FeedOptions{}); err != ErrNoFeedLink {
+		t.Errorf("expected ErrNoFeedLink, got %v", err)
+	}
+}
+
+func TestUUIDURN(t *testing.T) {
+	if u := uuidURN("FF755C6D7D9B4A5FBC4E41C07D622C65"); u != "urn:uuid:ff755c6d-7d9b-4a5f-bc4e-41c07d622c65" {
+		t.Errorf("unexpected urn: %s", u)
+	}
+}
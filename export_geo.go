@@ -0,0 +1,230 @@
+package dayone
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// regionSides is the number of sides of the polygons
+// approximating Region circles in GeoJSON.
+const regionSides = 32
+
+// earthRadius is the mean radius of the Earth in meters.
+const earthRadius = 6371008.8
+
+// locatedEntries reads the entries matching filter that have
+// a Location, oldest first.
+func (j *Journal) locatedEntries(filter FilterFunc) ([]*Entry, error) {
+	return j.exportEntries(func(e *Entry) bool {
+		return e.Location != nil && (filter == nil || filter(e))
+	})
+}
+
+// placeName returns the name to show for e on a map: its
+// title, or its local creation date if it has no title.
+func placeName(e *Entry) string {
+	if title := e.Title(); title != "" {
+		return title
+	}
+	return e.LocalCreationDate().Format("2006-01-02 15:04")
+}
+
+type geoJSONCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string            `json:"type"`
+	Geometry   geoJSONGeometry   `json:"geometry"`
+	Properties geoJSONProperties `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+type geoJSONProperties struct {
+	UUID      string   `json:"uuid"`
+	Title     string   `json:"title"`
+	Date      string   `json:"date"`
+	Tags      []string `json:"tags"`
+	Starred   bool     `json:"starred"`
+	PlaceName string   `json:"place_name,omitempty"`
+	Radius    float64  `json:"radius,omitempty"`
+}
+
+// ExportGeoJSON writes the entries matching filter that have
+// a Location to w as a GeoJSON FeatureCollection, oldest
+// first. Each entry is a Point feature, followed by a Polygon
+// feature approximating its Region circle if it has one; the
+// region's properties include its radius in meters. A nil
+// filter exports every entry with a location.
+// It returns the number of entries written.
+func (j *Journal) ExportGeoJSON(w io.Writer, filter FilterFunc) (int, error) {
+	entries, err := j.locatedEntries(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	c := geoJSONCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+	for _, e := range entries {
+		props := geoJSONProperties{
+			UUID:      e.UUID(),
+			Title:     e.Title(),
+			Date:      e.LocalCreationDate().Format(time.RFC3339),
+			Tags:      append([]string{}, e.Tags...),
+			Starred:   e.Starred,
+			PlaceName: e.Location.PlaceName,
+		}
+		c.Features = append(c.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{"Point", geoJSONPosition(e.Location.Coordinate)},
+			Properties: props,
+		})
+
+		r := e.Location.Region
+		if r == nil || r.Center == nil || r.Radius <= 0 {
+			continue
+		}
+		props.Radius = r.Radius
+		c.Features = append(c.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{"Polygon", [][][2]float64{circle(*r.Center, r.Radius, regionSides)}},
+			Properties: props,
+		})
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(c); err != nil {
+		return 0, err
+	}
+	return len(entries), bw.Flush()
+}
+
+func geoJSONPosition(c Coordinate) [2]float64 {
+	return [2]float64{c.Longitude, c.Latitude}
+}
+
+// circle returns a closed ring of GeoJSON positions, counter
+// clockwise, approximating the circle of radius meters around
+// center with the given number of sides.
+func circle(center Coordinate, radius float64, sides int) [][2]float64 {
+	lat := center.Latitude * math.Pi / 180
+	lon := center.Longitude * math.Pi / 180
+	d := radius / earthRadius
+
+	ring := make([][2]float64, sides+1)
+	for i := 0; i < sides; i++ {
+		bearing := -2 * math.Pi * float64(i) / float64(sides)
+		lat2 := math.Asin(math.Sin(lat)*math.Cos(d) + math.Cos(lat)*math.Sin(d)*math.Cos(bearing))
+		lon2 := lon + math.Atan2(math.Sin(bearing)*math.Sin(d)*math.Cos(lat), math.Cos(d)-math.Sin(lat)*math.Sin(lat2))
+		ring[i] = [2]float64{lon2 * 180 / math.Pi, lat2 * 180 / math.Pi}
+	}
+	ring[sides] = ring[0]
+	return ring
+}
+
+type kmlDocument struct {
+	XMLName    xml.Name       `xml:"http://www.opengis.net/kml/2.2 kml"`
+	Name       string         `xml:"Document>name"`
+	Placemarks []kmlPlacemark `xml:"Document>Placemark"`
+}
+
+type kmlPlacemark struct {
+	ID          string `xml:"id,attr"`
+	Name        string `xml:"name"`
+	Description string `xml:"description,omitempty"`
+	When        string `xml:"TimeStamp>when"`
+	Coordinates string `xml:"Point>coordinates"`
+}
+
+// ExportKML writes the entries matching filter that have a
+// Location to w as a KML document with a placemark for each,
+// oldest first, named after the entry's title and described
+// by its text. A nil filter exports every entry with a
+// location. It returns the number of entries written.
+func (j *Journal) ExportKML(w io.Writer, filter FilterFunc) (int, error) {
+	entries, err := j.locatedEntries(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	doc := kmlDocument{Name: "Day One"}
+	for _, e := range entries {
+		doc.Placemarks = append(doc.Placemarks, kmlPlacemark{
+			// An XML ID can't start with a digit, as uuids can.
+			ID:          "entry-" + e.UUID(),
+			Name:        placeName(e),
+			Description: strings.TrimSpace(e.EntryText),
+			When:        e.LocalCreationDate().Format(time.RFC3339),
+			Coordinates: formatFloat(e.Location.Longitude) + "," + formatFloat(e.Location.Latitude),
+		})
+	}
+
+	return len(entries), writeXML(w, doc)
+}
+
+type gpxDocument struct {
+	XMLName xml.Name   `xml:"http://www.topografix.com/GPX/1/1 gpx"`
+	Version string     `xml:"version,attr"`
+	Creator string     `xml:"creator,attr"`
+	Name    string     `xml:"trk>name"`
+	Points  []gpxPoint `xml:"trk>trkseg>trkpt"`
+}
+
+type gpxPoint struct {
+	Latitude  float64 `xml:"lat,attr"`
+	Longitude float64 `xml:"lon,attr"`
+	Time      string  `xml:"time"`
+	Name      string  `xml:"name"`
+}
+
+// ExportGPX writes the entries matching filter that have a
+// Location to w as a GPX track with a point for each entry,
+// ordered by CreationDate. A nil filter exports every entry
+// with a location. It returns the number of entries written.
+func (j *Journal) ExportGPX(w io.Writer, filter FilterFunc) (int, error) {
+	entries, err := j.locatedEntries(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	doc := gpxDocument{Version: "1.1", Creator: "go-dayone", Name: "Day One"}
+	for _, e := range entries {
+		doc.Points = append(doc.Points, gpxPoint{
+			Latitude:  e.Location.Latitude,
+			Longitude: e.Location.Longitude,
+			Time:      e.CreationDate.UTC().Format(time.RFC3339),
+			Name:      placeName(e),
+		})
+	}
+
+	return len(entries), writeXML(w, doc)
+}
+
+// writeXML writes v to w as an indented XML document.
+func writeXML(w io.Writer, v interface{}) error {
+	bw := bufio.NewWriter(w)
+	if _, err := io.WriteString(bw, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(bw)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(bw, "\n"); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
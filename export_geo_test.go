@@ -0,0 +1,154 @@
+package dayone
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportGeoJSON(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := NewJournal("./test_journals/default").ExportGeoJSON(&buf, func(e *Entry) bool {
+		return e.UUID() == syncUUID
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 entry, got %d", n)
+	}
+
+	var c geoJSONCollection
+	if err := json.Unmarshal(buf.Bytes(), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Type != "FeatureCollection" || len(c.Features) != 2 {
+		t.Fatalf("expected a point and a region, got %s", buf.String())
+	}
+
+	point := c.Features[0]
+	if point.Geometry.Type != "Point" {
+		t.Errorf("expected point, got %s", point.Geometry.Type)
+	}
+	expected := geoJSONProperties{
+		UUID:      syncUUID,
+		Title:     "title line",
+		Date:      "2014-09-23T20:52:11-05:00",
+		Tags:      []string{"bjj", "fitness"},
+		Starred:   true,
+		PlaceName: "199 Address Ln",
+	}
+	if !reflect.DeepEqual(point.Properties, expected) {
+		t.Errorf("unexpected properties: %+v", point.Properties)
+	}
+	if !strings.Contains(buf.String(), `"coordinates":[-87.`) {
+		t.Error("expected longitude first")
+	}
+
+	region := c.Features[1]
+	if region.Geometry.Type != "Polygon" || region.Properties.Radius == 0 {
+		t.Errorf("unexpected region: %+v", region)
+	}
+}
+
+func TestExportGeoJSONSkipsUnlocated(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanup()
+
+	if err := j.Write(&Entry{EntryText: "nowhere"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	n, err := j.ExportGeoJSON(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 || !strings.Contains(buf.String(), `"features":[]`) {
+		t.Errorf("expected no features, got %d: %s", n, buf.String())
+	}
+}
+
+func TestCircle(t *testing.T) {
+	center := Coordinate{Latitude: 40, Longitude: -88}
+	ring := circle(center, 1000, 8)
+
+	if len(ring) != 9 || ring[0] != ring[8] {
+		t.Fatalf("expected closed ring of 9 positions, got %v", ring)
+	}
+
+	// The first point is due north, one km is about 0.009 degrees.
+	if math.Abs(ring[0][0]+88) > 1e-9 || math.Abs(ring[0][1]-40.009) > 1e-3 {
+		t.Errorf("unexpected first position: %v", ring[0])
+	}
+
+	// Counter clockwise: the point after north is to the west.
+	if ring[1][0] >= -88 {
+		t.Errorf("expected ring to go west first, got %v", ring[1])
+	}
+}
+
+func TestExportKML(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := NewJournal("./test_journals/default").ExportKML(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 entries, got %d", n)
+	}
+
+	var doc kmlDocument
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Placemarks) != 2 {
+		t.Fatalf("expected 2 placemarks, got %d", len(doc.Placemarks))
+	}
+
+	ids := []string{doc.Placemarks[0].ID, doc.Placemarks[1].ID}
+	if !reflect.DeepEqual(ids, []string{"entry-" + photoUUID, "entry-" + syncUUID}) {
+		t.Errorf("unexpected placemark ids: %v", ids)
+	}
+
+	for _, p := range doc.Placemarks {
+		if p.ID != "entry-"+syncUUID {
+			continue
+		}
+		if p.Name != "title line" || p.When != "2014-09-23T20:52:11-05:00" {
+			t.Errorf("unexpected placemark: %+v", p)
+		}
+		if !strings.HasPrefix(p.Coordinates, "-87.") || !strings.Contains(p.Coordinates, ",39.98847099955192") {
+			t.Errorf("unexpected coordinates: %s", p.Coordinates)
+		}
+	}
+}
+
+func TestExportGPX(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := NewJournal("./test_journals/default").ExportGPX(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 entries, got %d", n)
+	}
+
+	var doc gpxDocument
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != "1.1" || len(doc.Points) != 2 {
+		t.Fatalf("unexpected gpx: %s", buf.String())
+	}
+	if doc.Points[1].Time < doc.Points[0].Time {
+		t.Error("expected points ordered by date")
+	}
+	if doc.Points[0].Latitude != 39.98847099955192 {
+		t.Errorf("unexpected latitude: %v", doc.Points[0].Latitude)
+	}
+}
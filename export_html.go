@@ -0,0 +1,432 @@
+package dayone
+
+import (
+	"encoding/json"
+	"github.com/juju/errgo"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ExportHTML writes a static site for browsing the entries
+// matching filter to dir. It needs no server: open index.html
+// in a browser. A nil filter exports every entry.
+//
+// The site has a page for each entry in year/month dirs with
+// its photo copied alongside, an index page for each month, a
+// page for each tag, a page of starred entries and a search
+// page backed by a client-side index in search-index.js.
+// It returns the number of entries written.
+func (j *Journal) ExportHTML(dir string, filter FilterFunc) (int, error) {
+	entries, err := j.exportEntries(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	s := &htmlSite{
+		dir:    dir,
+		months: make(map[string]*htmlList),
+		tags:   make(map[string]*htmlList),
+	}
+	for _, e := range entries {
+		if err := s.addEntry(j, e); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := s.writePages(); err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+type htmlSite struct {
+	dir      string
+	entries  []*htmlEntry
+	months   map[string]*htmlList // keyed by "2006/01"
+	tags     map[string]*htmlList // keyed by lowercase tag
+	tagOrder []*htmlList
+	starred  htmlList
+}
+
+type htmlEntry struct {
+	UUID    string
+	Title   string
+	Date    string
+	Path    string // relative to the site root
+	Photo   string // relative to the entry page
+	Body    template.HTML
+	Excerpt string
+	Tags    []*htmlList
+	Starred bool
+	Place   string
+	Weather string
+
+	text string // for the search index
+}
+
+// htmlList is a page listing entries, e.g. a month or tag.
+type htmlList struct {
+	Name    string
+	Path    string // relative to the site root
+	Entries []*htmlEntry
+}
+
+type htmlYear struct {
+	Year   string
+	Months []*htmlList
+}
+
+type htmlPage struct {
+	Root  string // relative path from the page to the site root
+	Title string
+	Data  interface{}
+}
+
+func (s *htmlSite) addEntry(j *Journal, e *Entry) error {
+	base := exportPath(e)
+	if err := os.MkdirAll(filepath.Join(s.dir, filepath.Dir(base)), 0755); err != nil {
+		return errgo.Mask(err)
+	}
+
+	photo, err := j.exportPhoto(e.UUID(), filepath.Join(s.dir, base+photoExt))
+	if err != nil {
+		return err
+	}
+
+	local := e.LocalCreationDate()
+	he := &htmlEntry{
+		UUID:    e.UUID(),
+		Title:   e.Title(),
+		Date:    local.Format("Monday, January 2, 2006 3:04 PM"),
+		Path:    filepath.ToSlash(base) + ".html",
+		Photo:   photo,
+		Body:    template.HTML(renderMarkdown(e.EntryText)),
+		Excerpt: excerpt(e.EntryText, 200),
+		Starred: e.Starred,
+		text:    e.EntryText,
+	}
+	if he.Title == "" {
+		he.Title = local.Format("January 2, 2006")
+	}
+	if l := e.Location; l != nil {
+		he.Place = joinNonEmpty(", ", l.PlaceName, l.Locality, l.AdministrativeArea, l.Country)
+	}
+	if w := e.Weather; w != nil {
+		he.Weather = joinNonEmpty(", ", w.Description, degrees(w.Celsius, "C"), degrees(w.Fahrenheit, "F"))
+	}
+
+	s.entries = append(s.entries, he)
+
+	key := local.Format("2006/01")
+	m, ok := s.months[key]
+	if !ok {
+		m = &htmlList{Name: local.Format("January 2006"), Path: key + "/index.html"}
+		s.months[key] = m
+	}
+	m.Entries = append(m.Entries, he)
+
+	// Tag pages ignore case, so "BJJ" and "bjj" are one tag.
+	seen := make(map[string]bool)
+	for _, tag := range e.Tags {
+		key := strings.ToLower(tag)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		t, ok := s.tags[key]
+		if !ok {
+			t = &htmlList{Name: tag, Path: "tags/" + s.tagSlug(tag) + ".html"}
+			s.tags[key] = t
+			s.tagOrder = append(s.tagOrder, t)
+		}
+		t.Entries = append(t.Entries, he)
+		he.Tags = append(he.Tags, t)
+	}
+
+	if e.Starred {
+		s.starred.Entries = append(s.starred.Entries, he)
+	}
+	return nil
+}
+
+// tagSlug returns a file name for tag that isn't
+// used by any other tag.
+func (s *htmlSite) tagSlug(tag string) string {
+	slug := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '-'
+	}, tag)
+	slug = strings.Trim(slug, "-")
+	if slug == "" {
+		slug = "tag"
+	}
+
+	taken := func(slug string) bool {
+		for _, t := range s.tagOrder {
+			if t.Path == "tags/"+slug+".html" {
+				return true
+			}
+		}
+		return false
+	}
+
+	unique := slug
+	for i := 2; taken(unique); i++ {
+		unique = slug + "-" + strconv.Itoa(i)
+	}
+	return unique
+}
+
+// years groups the months by year, in order.
+func (s *htmlSite) years() []*htmlYear {
+	var keys []string
+	for key := range s.months {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var years []*htmlYear
+	for _, key := range keys {
+		year := key[:4]
+		if len(years) == 0 || years[len(years)-1].Year != year {
+			years = append(years, &htmlYear{Year: year})
+		}
+		y := years[len(years)-1]
+		y.Months = append(y.Months, s.months[key])
+	}
+	return years
+}
+
+func (s *htmlSite) writePages() error {
+	sort.Slice(s.tagOrder, func(a, b int) bool {
+		return strings.ToLower(s.tagOrder[a].Name) < strings.ToLower(s.tagOrder[b].Name)
+	})
+
+	years := s.years()
+	if err := s.write("index.html", "index", "Journal", years); err != nil {
+		return err
+	}
+
+	for _, e := range s.entries {
+		if err := s.write(e.Path, "entry", e.Title, e); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range s.months {
+		if err := s.write(m.Path, "list", m.Name, m); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(s.dir, "tags"), 0755); err != nil {
+		return errgo.Mask(err)
+	}
+	if err := s.write("tags/index.html", "tags", "Tags", s.tagOrder); err != nil {
+		return err
+	}
+	for _, t := range s.tagOrder {
+		if err := s.write(t.Path, "list", "#"+t.Name, t); err != nil {
+			return err
+		}
+	}
+
+	s.starred.Name = "Starred"
+	if err := s.write("starred.html", "list", "Starred", &s.starred); err != nil {
+		return err
+	}
+
+	if err := s.write("search.html", "search", "Search", nil); err != nil {
+		return err
+	}
+	if err := s.writeSearchIndex(); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filepath.Join(s.dir, "style.css"), func(w io.Writer) error {
+		_, err := io.WriteString(w, htmlStyle)
+		return err
+	})
+}
+
+// write renders the named template to the page at path,
+// relative to the site root.
+func (s *htmlSite) write(path, name, title string, data interface{}) error {
+	page := htmlPage{
+		Root:  strings.Repeat("../", strings.Count(path, "/")),
+		Title: title,
+		Data:  data,
+	}
+	return writeFileAtomic(filepath.Join(s.dir, filepath.FromSlash(path)), func(w io.Writer) error {
+		return htmlTemplates.ExecuteTemplate(w, name, page)
+	})
+}
+
+type searchItem struct {
+	Title string   `json:"title"`
+	Date  string   `json:"date"`
+	URL   string   `json:"url"`
+	Tags  []string `json:"tags"`
+	Text  string   `json:"text"`
+}
+
+// writeSearchIndex writes the search index as a script rather
+// than JSON, since browsers won't fetch files from file:// URLs.
+func (s *htmlSite) writeSearchIndex() error {
+	items := make([]searchItem, 0, len(s.entries))
+	for _, e := range s.entries {
+		item := searchItem{
+			Title: e.Title,
+			Date:  e.Date,
+			URL:   e.Path,
+			Tags:  []string{},
+			Text:  strings.Join(strings.Fields(e.text), " "),
+		}
+		for _, t := range e.Tags {
+			item.Tags = append(item.Tags, t.Name)
+		}
+		items = append(items, item)
+	}
+
+	b, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filepath.Join(s.dir, "search-index.js"), func(w io.Writer) error {
+		_, err := io.WriteString(w, "var searchIndex = "+string(b)+";\n")
+		return err
+	})
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var out []string
+	for _, p := range parts {
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, sep)
+}
+
+func degrees(value, unit string) string {
+	if value == "" {
+		return ""
+	}
+	return value + "°" + unit
+}
+
+var htmlTemplates = template.Must(template.New("").Parse(`
+{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.Root}}style.css">
+</head>
+<body>
+<nav>
+<a href="{{.Root}}index.html">Journal</a>
+<a href="{{.Root}}tags/index.html">Tags</a>
+<a href="{{.Root}}starred.html">Starred</a>
+<a href="{{.Root}}search.html">Search</a>
+</nav>
+<main>
+{{end}}
+
+{{define "footer"}}</main>
+</body>
+</html>
+{{end}}
+
+{{define "index"}}{{template "header" .}}<h1>Journal</h1>
+{{range .Data}}<h2>{{.Year}}</h2>
+<ul>
+{{range .Months}}<li><a href="{{$.Root}}{{.Path}}">{{.Name}}</a> ({{len .Entries}})</li>
+{{end}}</ul>
+{{else}}<p>No entries.</p>
+{{end}}{{template "footer" .}}{{end}}
+
+{{define "list"}}{{template "header" .}}<h1>{{.Title}}</h1>
+<ul class="entries">
+{{range .Data.Entries}}<li>
+<a href="{{$.Root}}{{.Path}}">{{.Title}}</a>{{if .Starred}} ★{{end}}
+<time>{{.Date}}</time>
+{{with .Excerpt}}<p>{{.}}</p>
+{{end}}</li>
+{{else}}<li>No entries.</li>
+{{end}}</ul>
+{{template "footer" .}}{{end}}
+
+{{define "tags"}}{{template "header" .}}<h1>Tags</h1>
+<ul>
+{{range .Data}}<li><a href="{{$.Root}}{{.Path}}">{{.Name}}</a> ({{len .Entries}})</li>
+{{else}}<li>No tags.</li>
+{{end}}</ul>
+{{template "footer" .}}{{end}}
+
+{{define "entry"}}{{template "header" .}}{{with .Data}}<article>
+<time>{{.Date}}</time>{{if .Starred}} ★{{end}}
+{{with .Photo}}<img class="photo" src="{{.}}" alt="">
+{{end}}{{.Body}}
+<footer>
+{{with .Tags}}<p class="tags">{{range .}}<a href="{{$.Root}}{{.Path}}">#{{.Name}}</a> {{end}}</p>
+{{end}}{{with .Place}}<p class="place">{{.}}</p>
+{{end}}{{with .Weather}}<p class="weather">{{.}}</p>
+{{end}}</footer>
+</article>
+{{end}}{{template "footer" .}}{{end}}
+
+{{define "search"}}{{template "header" .}}<h1>Search</h1>
+<input id="q" type="search" placeholder="Search entries" autofocus>
+<ul id="results" class="entries"></ul>
+<script src="search-index.js"></script>
+<script>
+var input = document.getElementById("q");
+var results = document.getElementById("results");
+input.oninput = function() {
+	var words = input.value.toLowerCase().split(/\s+/).filter(Boolean);
+	results.innerHTML = "";
+	if (!words.length) return;
+	searchIndex.forEach(function(e) {
+		var text = (e.title + " " + e.text + " " + e.tags.join(" ")).toLowerCase();
+		if (!words.every(function(w) { return text.indexOf(w) >= 0; })) return;
+		var li = document.createElement("li");
+		var a = document.createElement("a");
+		a.href = e.url;
+		a.textContent = e.title;
+		var time = document.createElement("time");
+		time.textContent = e.date;
+		li.appendChild(a);
+		li.appendChild(time);
+		results.appendChild(li);
+	});
+};
+</script>
+{{template "footer" .}}{{end}}
+`))
+
+const htmlStyle = `body { font: 16px/1.5 Georgia, serif; color: #222; margin: 0; }
+nav { background: #2f7ab9; padding: 0.5em 1em; }
+nav a { color: #fff; margin-right: 1em; text-decoration: none; }
+main { max-width: 40em; margin: 0 auto; padding: 1em; }
+time { display: block; color: #888; font-size: 0.9em; }
+ul.entries { list-style: none; padding: 0; }
+ul.entries li { margin-bottom: 1em; }
+ul.entries p { margin: 0.25em 0; }
+img { max-width: 100%; }
+pre { overflow-x: auto; background: #f4f4f4; padding: 0.5em; }
+blockquote { border-left: 3px solid #ccc; margin-left: 0; padding-left: 1em; color: #555; }
+footer { border-top: 1px solid #eee; margin-top: 2em; color: #666; font-size: 0.9em; }
+input[type=search] { width: 100%; font-size: 1.2em; padding: 0.25em; }
+`
@@ -0,0 +1,141 @@
+package dayone
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportHTML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dayone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	j := NewJournal("./test_journals/default")
+	n, err := j.ExportHTML(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 entries, got %d", n)
+	}
+
+	read := func(path string) string {
+		b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	contains := func(path string, parts ...string) {
+		page := read(path)
+		for _, s := range parts {
+			if !strings.Contains(page, s) {
+				t.Errorf("expected %s to contain %q", path, s)
+			}
+		}
+	}
+
+	contains("index.html",
+		`<h2>2014</h2>`,
+		`<a href="2014/09/index.html">September 2014</a> (2)`,
+	)
+	contains("2014/09/index.html",
+		`<link rel="stylesheet" href="../../style.css">`,
+		`<a href="../../2014/09/2014-09-23-FF755C6D7D9B4A5FBC4E41C07D622C65.html">title line</a> ★`,
+		`<p>body line</p>`,
+	)
+	contains("2014/09/2014-09-23-871D0F435D7B469C9429CD441A9E74B5.html",
+		`<title>title line</title>`,
+		`<img class="photo" src="2014-09-23-871D0F435D7B469C9429CD441A9E74B5.jpg" alt="">`,
+		"<h1>title line</h1>\n<p>body line</p>",
+		`<a href="../../tags/bjj.html">#bjj</a>`,
+		`<p class="place">199 Address Ln, Somewhere, TX, United States</p>`,
+	)
+	contains("tags/index.html", `<a href="../tags/bjj.html">bjj</a> (2)`)
+	contains("tags/fitness.html", `<h1>#fitness</h1>`)
+	contains("starred.html", `FF755C6D7D9B4A5FBC4E41C07D622C65.html`)
+	contains("search.html", `<script src="search-index.js"></script>`)
+	contains("search-index.js",
+		`var searchIndex = [{"title":"title line",`,
+		`"url":"2014/09/2014-09-23-`,
+		`"tags":["bjj","fitness"],"text":"#title line body line"}`,
+	)
+
+	if _, err := os.Stat(filepath.Join(dir, "2014", "09", "2014-09-23-871D0F435D7B469C9429CD441A9E74B5.jpg")); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "style.css")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExportHTMLEscapes(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanup()
+
+	e := &Entry{EntryText: "<b>title</b>\n<script>x</script>", Tags: []string{"a/b", "A-B"}}
+	if err := j.Write(e); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "dayone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := j.ExportHTML(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, exportPath(e)+".html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(b)
+	if strings.Contains(page, "<script>x") || strings.Contains(page, "<b>title") {
+		t.Error("expected entry text to be escaped")
+	}
+
+	// Both tags slug to a-b, so the second gets a suffix.
+	for _, name := range []string{"a-b.html", "a-b-2.html"} {
+		if _, err := os.Stat(filepath.Join(dir, "tags", name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestExportHTMLTagsIgnoreCase(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	e := newEntry()
+	e.EntryText = "rolling"
+	e.Tags = []string{"BJJ", "bjj"}
+	if err := j.Write(e); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "dayone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := j.ExportHTML(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "tags", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `<a href="../tags/bjj.html">bjj</a> (3)`) {
+		t.Errorf("expected each entry listed once under bjj:\n%s", b)
+	}
+}
@@ -0,0 +1,291 @@
+package dayone
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ICSOptions configures Journal.ExportICS.
+// The zero value uses the defaults.
+type ICSOptions struct {
+	// Filter selects the entries to export.
+	// nil exports every entry.
+	Filter FilterFunc
+
+	// AllDay makes each entry an all-day event on its
+	// local creation date rather than a timed event.
+	AllDay bool
+}
+
+const (
+	icsDate     = "20060102"
+	icsDateTime = "20060102T150405"
+)
+
+// ExportICS writes the entries matching opts.Filter to w as
+// an iCalendar file with a VEVENT for each, oldest first.
+// Timed events start at the entry's CreationDate in its
+// TimeZone, described by a VTIMEZONE block built from the
+// tz database for the years the entries span. opts may be
+// nil. It returns the number of entries written.
+func (j *Journal) ExportICS(w io.Writer, opts *ICSOptions) (int, error) {
+	var o ICSOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	entries, err := j.exportEntries(o.Filter)
+	if err != nil {
+		return 0, err
+	}
+
+	iw := &icsWriter{w: bufio.NewWriter(w)}
+	iw.line("BEGIN:VCALENDAR")
+	iw.line("VERSION:2.0")
+	iw.line("PRODID:-//go-dayone//Day One export//EN")
+	iw.line("CALSCALE:GREGORIAN")
+
+	if !o.AllDay {
+		for _, z := range icsZones(entries) {
+			iw.timezone(z)
+		}
+	}
+
+	for _, e := range entries {
+		iw.event(e, o.AllDay)
+	}
+
+	iw.line("END:VCALENDAR")
+	if iw.err != nil {
+		return 0, iw.err
+	}
+	return len(entries), iw.w.Flush()
+}
+
+// icsZone is a time zone used by some of the exported
+// entries, between the years first and last.
+type icsZone struct {
+	loc         *time.Location
+	first, last int
+}
+
+// icsZones returns the named time zones the entries are
+// in, sorted by name.
+func icsZones(entries []*Entry) []*icsZone {
+	zones := make(map[string]*icsZone)
+	var names []string
+	for _, e := range entries {
+		local := e.LocalCreationDate()
+		name, ok := icsZoneName(local)
+		if !ok {
+			continue
+		}
+
+		year := local.Year()
+		z := zones[name]
+		if z == nil {
+			z = &icsZone{local.Location(), year, year}
+			zones[name] = z
+			names = append(names, name)
+		}
+		if year < z.first {
+			z.first = year
+		}
+		if year > z.last {
+			z.last = year
+		}
+	}
+
+	sort.Strings(names)
+	out := make([]*icsZone, len(names))
+	for i, name := range names {
+		out[i] = zones[name]
+	}
+	return out
+}
+
+// icsZoneName returns the TZID for t's location, or false if
+// t should be written in UTC.
+func icsZoneName(t time.Time) (string, bool) {
+	name := t.Location().String()
+	return name, name != "UTC" && name != "Local"
+}
+
+// zoneTransition is a change of offset at a point in time.
+type zoneTransition struct {
+	at       time.Time
+	from, to int
+	name     string
+	dst      bool
+}
+
+// transitions returns the state of z at the start of its
+// first year as a transition from and to the same offset,
+// followed by every change of offset until the end of its
+// last year.
+func (z *icsZone) transitions() []zoneTransition {
+	start := time.Date(z.first, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(z.last+1, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	name, offset := start.In(z.loc).Zone()
+	out := []zoneTransition{{start, offset, offset, name, start.In(z.loc).IsDST()}}
+
+	const step = 24 * time.Hour
+	for t := start; t.Before(end); t = t.Add(step) {
+		next := t.Add(step)
+		_, to := next.In(z.loc).Zone()
+		if to == offset {
+			continue
+		}
+
+		// Find the first second with the new offset.
+		lo, hi := t, next
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if _, o := mid.In(z.loc).Zone(); o == offset {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+
+		local := hi.In(z.loc)
+		name, to = local.Zone()
+		out = append(out, zoneTransition{hi, offset, to, name, local.IsDST()})
+		offset = to
+	}
+	return out
+}
+
+// icsWriter writes content lines, folded and ending with
+// CRLF, remembering the first error.
+type icsWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line writes a content line, folding it so no line is longer
+// than 75 octets without splitting a UTF-8 sequence.
+func (iw *icsWriter) line(s string) {
+	if iw.err != nil {
+		return
+	}
+
+	limit := 75
+	for len(s) > limit {
+		n := limit
+		for n > 0 && !utf8.RuneStart(s[n]) {
+			n--
+		}
+		if n == 0 {
+			// Invalid UTF-8 with no rune start to split at.
+			n = limit
+		}
+		iw.write(s[:n] + "\r\n ")
+		s = s[n:]
+		// The leading space counts towards the next line.
+		limit = 74
+	}
+	iw.write(s + "\r\n")
+}
+
+func (iw *icsWriter) write(s string) {
+	if iw.err == nil {
+		_, iw.err = iw.w.WriteString(s)
+	}
+}
+
+func (iw *icsWriter) timezone(z *icsZone) {
+	iw.line("BEGIN:VTIMEZONE")
+	iw.line("TZID:" + z.loc.String())
+	for _, t := range z.transitions() {
+		kind := "STANDARD"
+		if t.dst {
+			kind = "DAYLIGHT"
+		}
+		iw.line("BEGIN:" + kind)
+		iw.line("DTSTART:" + t.at.Add(time.Duration(t.from)*time.Second).Format(icsDateTime))
+		iw.line("TZOFFSETFROM:" + icsOffset(t.from))
+		iw.line("TZOFFSETTO:" + icsOffset(t.to))
+		iw.line("TZNAME:" + icsText(t.name))
+		iw.line("END:" + kind)
+	}
+	iw.line("END:VTIMEZONE")
+}
+
+func (iw *icsWriter) event(e *Entry, allDay bool) {
+	local := e.LocalCreationDate()
+
+	iw.line("BEGIN:VEVENT")
+	iw.line("UID:" + e.UUID() + "@dayone")
+	iw.line("DTSTAMP:" + e.CreationDate.UTC().Format(icsDateTime) + "Z")
+	if allDay {
+		iw.line("DTSTART;VALUE=DATE:" + local.Format(icsDate))
+		iw.line("DTEND;VALUE=DATE:" + local.AddDate(0, 0, 1).Format(icsDate))
+	} else if name, ok := icsZoneName(local); ok {
+		iw.line("DTSTART;TZID=" + name + ":" + local.Format(icsDateTime))
+	} else {
+		iw.line("DTSTART:" + e.CreationDate.UTC().Format(icsDateTime) + "Z")
+	}
+
+	iw.line("SUMMARY:" + icsText(placeName(e)))
+	if body := entryBody(e.EntryText); body != "" {
+		iw.line("DESCRIPTION:" + icsText(body))
+	}
+	if loc := e.Location; loc != nil {
+		if loc.PlaceName != "" {
+			iw.line("LOCATION:" + icsText(loc.PlaceName))
+		}
+		iw.line("GEO:" + formatFloat(loc.Latitude) + ";" + formatFloat(loc.Longitude))
+	}
+	if len(e.Tags) > 0 {
+		tags := make([]string, len(e.Tags))
+		for i, tag := range e.Tags {
+			tags[i] = icsText(tag)
+		}
+		iw.line("CATEGORIES:" + strings.Join(tags, ","))
+	}
+	iw.line("END:VEVENT")
+}
+
+// entryBody returns the text after the title line, trimmed.
+func entryBody(text string) string {
+	lines := strings.SplitN(strings.TrimSpace(text), "\n", 2)
+	if len(lines) < 2 {
+		return ""
+	}
+	return strings.TrimSpace(lines[1])
+}
+
+var icsEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+// icsText escapes s as an iCalendar TEXT value.
+func icsText(s string) string {
+	return icsEscaper.Replace(s)
+}
+
+// icsOffset formats a UTC offset in seconds as e.g. -0500.
+func icsOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	s := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		s += fmt.Sprintf("%02d", seconds%60)
+	}
+	return s
+}
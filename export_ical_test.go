@@ -0,0 +1,133 @@
+package dayone
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exportICS(t *testing.T, opts *ICSOptions) string {
+	var buf bytes.Buffer
+	n, err := NewJournal("./test_journals/default").ExportICS(&buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 entries, got %d", n)
+	}
+	return buf.String()
+}
+
+func TestExportICS(t *testing.T) {
+	out := exportICS(t, nil)
+
+	if !strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n") || !strings.HasSuffix(out, "END:VCALENDAR\r\n") {
+		t.Errorf("unexpected calendar:\n%s", out)
+	}
+	for _, expected := range []string{
+		"UID:" + syncUUID + "@dayone\r\n",
+		"DTSTART;TZID=America/Chicago:20140923T205211\r\n",
+		"SUMMARY:title line\r\n",
+		"DESCRIPTION:body line\r\n",
+		"LOCATION:199 Address Ln\r\n",
+		"GEO:39.98847099955192;-87.",
+		"CATEGORIES:bjj,fitness\r\n",
+		"BEGIN:VTIMEZONE\r\nTZID:America/Chicago\r\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q in:\n%s", expected, out)
+		}
+	}
+	if strings.Count(out, "BEGIN:VTIMEZONE") != 1 {
+		t.Error("expected one VTIMEZONE per zone")
+	}
+}
+
+func TestExportICSAllDay(t *testing.T) {
+	out := exportICS(t, &ICSOptions{AllDay: true})
+
+	if !strings.Contains(out, "DTSTART;VALUE=DATE:20140923\r\nDTEND;VALUE=DATE:20140924\r\n") {
+		t.Errorf("expected all-day event on local date:\n%s", out)
+	}
+	if strings.Contains(out, "VTIMEZONE") {
+		t.Error("expected no VTIMEZONE for all-day events")
+	}
+}
+
+func TestICSZoneTransitions(t *testing.T) {
+	loc, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	var buf bytes.Buffer
+	iw := &icsWriter{w: bufio.NewWriter(&buf)}
+	iw.timezone(&icsZone{loc, 2014, 2014})
+	iw.w.Flush()
+
+	expected := "BEGIN:VTIMEZONE\r\nTZID:America/Chicago\r\n" +
+		"BEGIN:STANDARD\r\nDTSTART:20131231T180000\r\nTZOFFSETFROM:-0600\r\nTZOFFSETTO:-0600\r\nTZNAME:CST\r\nEND:STANDARD\r\n" +
+		"BEGIN:DAYLIGHT\r\nDTSTART:20140309T020000\r\nTZOFFSETFROM:-0600\r\nTZOFFSETTO:-0500\r\nTZNAME:CDT\r\nEND:DAYLIGHT\r\n" +
+		"BEGIN:STANDARD\r\nDTSTART:20141102T020000\r\nTZOFFSETFROM:-0500\r\nTZOFFSETTO:-0600\r\nTZNAME:CST\r\nEND:STANDARD\r\n" +
+		"END:VTIMEZONE\r\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected timezone:\n%s", buf.String())
+	}
+}
+
+func TestICSLineFolding(t *testing.T) {
+	var buf bytes.Buffer
+	iw := &icsWriter{w: bufio.NewWriter(&buf)}
+	long := "DESCRIPTION:" + strings.Repeat("é", 100)
+	iw.line(long)
+	iw.w.Flush()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+	if len(lines) < 3 {
+		t.Fatalf("expected folded lines, got %q", lines)
+	}
+	for i, l := range lines {
+		if len(l) > 75 {
+			t.Errorf("line %d is %d octets", i, len(l))
+		}
+		if i > 0 && !strings.HasPrefix(l, " ") {
+			t.Errorf("expected continuation line %d to start with a space", i)
+		}
+	}
+	if unfolded := strings.Replace(buf.String(), "\r\n ", "", -1); unfolded != long+"\r\n" {
+		t.Errorf("unexpected unfolded line: %q", unfolded)
+	}
+}
+
+func TestICSLineFoldingInvalidUTF8(t *testing.T) {
+	e := newEntry()
+	e.EntryText = "title\n\n" + strings.Repeat("\x80", 200)
+
+	var buf bytes.Buffer
+	iw := &icsWriter{w: bufio.NewWriter(&buf)}
+	iw.event(e, false)
+	iw.w.Flush()
+
+	for i, l := range strings.Split(buf.String(), "\r\n") {
+		if len(l) > 75 {
+			t.Errorf("line %d is %d octets", i, len(l))
+		}
+	}
+	if !strings.Contains(strings.Replace(buf.String(), "\r\n ", "", -1), strings.Repeat("\x80", 200)) {
+		t.Error("expected the text to survive folding")
+	}
+}
+
+func TestICSText(t *testing.T) {
+	if s := icsText("a, b; c\\d\ne"); s != `a\, b\; c\\d\ne` {
+		t.Errorf("unexpected escaping: %s", s)
+	}
+	if s := icsOffset(-5 * 3600); s != "-0500" {
+		t.Errorf("unexpected offset: %s", s)
+	}
+	if s := icsOffset(5*3600 + 30*60); s != "+0530" {
+		t.Errorf("unexpected offset: %s", s)
+	}
+}
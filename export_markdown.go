@@ -0,0 +1,171 @@
+package dayone
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/juju/errgo"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ExportMarkdown writes each entry matching filter to a
+// Markdown file with YAML front matter, in year/month dirs
+// under dir named after the local creation date and uuid.
+// An entry's photo is copied alongside and linked at the top
+// of the text. A nil filter exports every entry.
+// It returns the number of entries written.
+func (j *Journal) ExportMarkdown(dir string, filter FilterFunc) (int, error) {
+	entries, err := j.exportEntries(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, e := range entries {
+		if err := j.exportMarkdownEntry(dir, e); err != nil {
+			return i, err
+		}
+	}
+	return len(entries), nil
+}
+
+func (j *Journal) exportMarkdownEntry(dir string, e *Entry) error {
+	path := filepath.Join(dir, exportPath(e))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errgo.Mask(err)
+	}
+
+	photo, err := j.exportPhoto(e.UUID(), path+photoExt)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path+".md", func(w io.Writer) error {
+		return writeMarkdown(w, e, photo)
+	})
+}
+
+// exportPhoto copies the photo for uuid to path and returns
+// the file name to link to, or "" if the entry has no photo.
+func (j *Journal) exportPhoto(uuid, path string) (string, error) {
+	r, err := j.OpenPhoto(uuid)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	err = writeFileAtomic(path, func(w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(path), nil
+}
+
+// writeMarkdown writes e as Markdown with YAML front matter,
+// linking to photo if it isn't empty.
+func writeMarkdown(w io.Writer, e *Entry, photo string) error {
+	var b bytes.Buffer
+
+	b.WriteString("---\n")
+	writeFrontMatter(&b, e)
+	b.WriteString("---\n\n")
+
+	if photo != "" {
+		fmt.Fprintf(&b, "![](%s)\n\n", photo)
+	}
+
+	if e.EntryText != "" {
+		b.WriteString(e.EntryText)
+		if e.EntryText[len(e.EntryText)-1] != '\n' {
+			b.WriteByte('\n')
+		}
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func writeFrontMatter(b *bytes.Buffer, e *Entry) {
+	yamlField(b, "", "uuid", yamlString(e.UUID()))
+	yamlField(b, "", "date", e.LocalCreationDate().Format(time.RFC3339))
+	if e.TimeZone != "" {
+		yamlField(b, "", "time_zone", yamlString(e.TimeZone))
+	}
+
+	if len(e.Tags) == 0 {
+		yamlField(b, "", "tags", "[]")
+	} else {
+		b.WriteString("tags:\n")
+		for _, tag := range e.Tags {
+			fmt.Fprintf(b, "  - %s\n", yamlString(tag))
+		}
+	}
+
+	yamlField(b, "", "starred", strconv.FormatBool(e.Starred))
+
+	if l := e.Location; l != nil {
+		b.WriteString("location:\n")
+		yamlOptional(b, "place_name", l.PlaceName)
+		yamlOptional(b, "locality", l.Locality)
+		yamlOptional(b, "administrative_area", l.AdministrativeArea)
+		yamlOptional(b, "country", l.Country)
+		yamlField(b, "  ", "latitude", formatFloat(l.Latitude))
+		yamlField(b, "  ", "longitude", formatFloat(l.Longitude))
+	}
+
+	if wt := e.Weather; wt != nil {
+		b.WriteString("weather:\n")
+		yamlOptional(b, "celsius", wt.Celsius)
+		yamlOptional(b, "fahrenheit", wt.Fahrenheit)
+		yamlOptional(b, "description", wt.Description)
+		if wt.RelativeHumidity != 0 {
+			yamlField(b, "  ", "relative_humidity", formatFloat(wt.RelativeHumidity))
+		}
+		if wt.WindSpeedKPH != 0 {
+			yamlField(b, "  ", "wind_speed_kph", formatFloat(wt.WindSpeedKPH))
+		}
+	}
+
+	if m := e.Music; m != nil {
+		b.WriteString("music:\n")
+		yamlOptional(b, "artist", m.Artist)
+		yamlOptional(b, "track", m.Track)
+		yamlOptional(b, "album", m.Album)
+		yamlOptional(b, "album_year", m.AlbumYear)
+	}
+
+	if e.Activity != "" {
+		yamlField(b, "", "activity", yamlString(e.Activity))
+	}
+	if e.StepCount != 0 {
+		yamlField(b, "", "step_count", strconv.FormatUint(e.StepCount, 10))
+	}
+}
+
+func yamlField(b *bytes.Buffer, indent, key, value string) {
+	fmt.Fprintf(b, "%s%s: %s\n", indent, key, value)
+}
+
+// yamlOptional writes a nested string field unless it's empty.
+func yamlOptional(b *bytes.Buffer, key, value string) {
+	if value != "" {
+		yamlField(b, "  ", key, yamlString(value))
+	}
+}
+
+// yamlString quotes s as a YAML double-quoted scalar. The Go
+// escapes strconv uses are all valid YAML escapes too.
+func yamlString(s string) string {
+	return strconv.Quote(s)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,108 @@
+package dayone
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportMarkdown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dayone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	j := NewJournal("./test_journals/default")
+	n, err := j.ExportMarkdown(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 entries, got %d", n)
+	}
+
+	base := filepath.Join(dir, "2014", "09", "2014-09-23-FF755C6D7D9B4A5FBC4E41C07D622C65")
+	b, err := ioutil.ReadFile(base + ".md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := string(b)
+
+	for _, s := range []string{
+		"---\nuuid: \"FF755C6D7D9B4A5FBC4E41C07D622C65\"\n",
+		"date: 2014-09-23T20:52:11-05:00\n",
+		"tags:\n  - \"bjj\"\n  - \"fitness\"\n",
+		"starred: true\n",
+		"location:\n  place_name: \"199 Address Ln\"\n",
+		"  latitude: 39.98847099955192\n",
+		"weather:\n  celsius: \"24\"\n",
+		"activity: \"Automotive\"\n",
+		"step_count: 1043\n",
+		"---\n\n#title line\n\nbody line\n",
+	} {
+		if !strings.Contains(md, s) {
+			t.Errorf("expected markdown to contain %q", s)
+		}
+	}
+
+	photoBase := filepath.Join(filepath.Dir(base), "2014-09-23-871D0F435D7B469C9429CD441A9E74B5")
+	b, err = ioutil.ReadFile(photoBase + ".md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "---\n\n![](2014-09-23-871D0F435D7B469C9429CD441A9E74B5.jpg)\n\n") {
+		t.Error("expected photo link")
+	}
+	if _, err := os.Stat(photoBase + ".jpg"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExportMarkdownFilter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dayone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	j := NewJournal("./test_journals/default")
+	n, err := j.ExportMarkdown(dir, func(e *Entry) bool {
+		return e.UUID() == "FF755C6D7D9B4A5FBC4E41C07D622C65"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 entry, got %d", n)
+	}
+}
+
+func TestWriteMarkdownMinimal(t *testing.T) {
+	e := &Entry{
+		uuid:         "FF755C6D7D9B4A5FBC4E41C07D622C65",
+		CreationDate: time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC),
+		EntryText:    "say \"hi\"",
+		Tags:         []string{"a: b"},
+	}
+
+	var b bytes.Buffer
+	if err := writeMarkdown(&b, e, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "---\n" +
+		"uuid: \"FF755C6D7D9B4A5FBC4E41C07D622C65\"\n" +
+		"date: 2015-01-02T03:04:05Z\n" +
+		"tags:\n  - \"a: b\"\n" +
+		"starred: false\n" +
+		"---\n\n" +
+		"say \"hi\"\n"
+	if b.String() != expected {
+		t.Errorf("unexpected markdown:\n%s", b.String())
+	}
+}
@@ -0,0 +1,499 @@
+package dayone
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"github.com/juju/errgo"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PDFOptions configures Journal.ExportPDF.
+// The zero value uses the defaults.
+type PDFOptions struct {
+	// Title is the title on the cover page.
+	// Defaults to "Day One".
+	Title string
+
+	// Author is shown on the cover page, if set.
+	Author string
+
+	// Filter selects the entries to include.
+	// nil includes every entry.
+	Filter FilterFunc
+}
+
+// US Letter page layout, in points.
+const (
+	pdfPageWidth   = 612
+	pdfPageHeight  = 792
+	pdfMargin      = 72
+	pdfTextWidth   = pdfPageWidth - 2*pdfMargin
+	pdfPhotoHeight = 320
+)
+
+// ExportPDF writes the entries matching opts.Filter to w as a
+// printable PDF, oldest first, after a cover page. Each entry
+// has its date, title, photo, body text wrapped to the page,
+// tags and a line of location and weather, and entries flow
+// onto new pages as they fill. Text is set in Helvetica, so
+// characters outside Windows-1252 are printed as '?'.
+// opts may be nil. It returns the number of entries written.
+func (j *Journal) ExportPDF(w io.Writer, opts *PDFOptions) (int, error) {
+	var o PDFOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Title == "" {
+		o.Title = "Day One"
+	}
+
+	entries, err := j.exportEntries(o.Filter)
+	if err != nil {
+		return 0, err
+	}
+
+	d := newPDFDocument()
+	d.cover(o, entries)
+	for _, e := range entries {
+		if err := j.layoutPDFEntry(d, e); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := d.WriteTo(w); err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+func (d *pdfDocument) cover(o PDFOptions, entries []*Entry) {
+	d.newPage()
+	d.y = pdfPageHeight * 0.6
+	d.centered(o.Title, pdfBold, 28)
+	if o.Author != "" {
+		d.y -= 12
+		d.centered(o.Author, pdfRegular, 16)
+	}
+	if len(entries) > 0 {
+		first := entries[0].LocalCreationDate().Format("January 2, 2006")
+		last := entries[len(entries)-1].LocalCreationDate().Format("January 2, 2006")
+		dates := first
+		if last != first {
+			dates += " – " + last
+		}
+		d.y -= 24
+		d.centered(dates, pdfRegular, 12)
+	}
+	count := strconv.Itoa(len(entries)) + " entries"
+	if len(entries) == 1 {
+		count = "1 entry"
+	}
+	d.centered(count, pdfRegular, 12)
+
+	// Entries start on the page after the cover.
+	if len(entries) > 0 {
+		d.newPage()
+	}
+}
+
+func (j *Journal) layoutPDFEntry(d *pdfDocument, e *Entry) error {
+	// Keep the date, title and first lines of text together.
+	d.need(80)
+
+	date := e.LocalCreationDate().Format("Monday, January 2, 2006 3:04 PM")
+	if e.Starred {
+		date += " · Starred"
+	}
+	d.paragraph(date, pdfRegular, 10, 0.4)
+
+	if title := e.Title(); title != "" {
+		d.y -= 4
+		d.paragraph(title, pdfBold, 16, 0)
+	}
+
+	if err := j.layoutPDFPhoto(d, e.UUID()); err != nil {
+		return err
+	}
+
+	d.y -= 6
+	for _, p := range strings.Split(entryBody(e.EntryText), "\n") {
+		if strings.TrimSpace(p) == "" {
+			d.y -= 6
+			continue
+		}
+		d.paragraph(p, pdfRegular, 11, 0)
+	}
+
+	if len(e.Tags) > 0 {
+		d.y -= 6
+		d.paragraph("#"+strings.Join(e.Tags, " #"), pdfRegular, 10, 0.4)
+	}
+
+	var place, weather string
+	if l := e.Location; l != nil {
+		place = joinNonEmpty(", ", l.PlaceName, l.Locality, l.AdministrativeArea, l.Country)
+	}
+	if w := e.Weather; w != nil {
+		weather = joinNonEmpty(", ", w.Description, degrees(w.Celsius, "C"), degrees(w.Fahrenheit, "F"))
+	}
+	if line := joinNonEmpty(" · ", place, weather); line != "" {
+		d.paragraph(line, pdfRegular, 10, 0.4)
+	}
+
+	d.y -= 28
+	return nil
+}
+
+// layoutPDFPhoto embeds the photo for uuid, if it has one,
+// scaled to fit the text width and at most pdfPhotoHeight.
+// Photos that can't be decoded as JPEG are left out.
+func (j *Journal) layoutPDFPhoto(d *pdfDocument, uuid string) error {
+	r, err := j.OpenPhoto(uuid)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return errgo.Mask(err)
+	}
+
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width == 0 || cfg.Height == 0 {
+		return nil
+	}
+
+	w, h := float64(pdfTextWidth), float64(pdfTextWidth)*float64(cfg.Height)/float64(cfg.Width)
+	if h > pdfPhotoHeight {
+		w, h = w*pdfPhotoHeight/h, pdfPhotoHeight
+	}
+
+	d.y -= 8
+	d.need(h)
+	d.image(d.addJPEG(data, cfg), w, h)
+	return nil
+}
+
+// pdfFont is one of the standard Type 1 fonts, which PDF
+// readers provide, with its glyph widths in thousandths of
+// the font size.
+type pdfFont struct {
+	resource string
+	name     string
+	widths   [95]int // for ' ' through '~'
+}
+
+var pdfRegular = &pdfFont{"F1", "Helvetica", [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0 to ?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @ to O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P to _
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // ` to o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p to ~
+}}
+
+var pdfBold = &pdfFont{"F2", "Helvetica-Bold", [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611, // 0 to ?
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778, // @ to O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556, // P to _
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611, // ` to o
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584, // p to ~
+}}
+
+// winAnsi maps the characters outside Latin-1 that
+// WinAnsiEncoding has to their codes.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// encode returns s in WinAnsiEncoding, with '?' for
+// characters it doesn't have.
+func (f *pdfFont) encode(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r >= ' ' && r <= '~', r >= 0xa0 && r <= 0xff:
+			b = append(b, byte(r))
+		case winAnsi[r] != 0:
+			b = append(b, winAnsi[r])
+		case r == '\t':
+			b = append(b, ' ')
+		default:
+			b = append(b, '?')
+		}
+	}
+	return b
+}
+
+// width returns the width of s in points at the given size.
+// Characters outside ASCII are given the width of a digit,
+// or of an em for the wide punctuation.
+func (f *pdfFont) width(s string, size float64) float64 {
+	total := 0
+	for _, c := range f.encode(s) {
+		switch {
+		case c >= ' ' && c <= '~':
+			total += f.widths[c-' ']
+		case c == 0x85, c == 0x89, c == 0x97, c == 0x99:
+			total += 1000
+		default:
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// wrap breaks s into lines no wider than width at the given
+// size, breaking between words where it can and within words
+// too long for a line.
+func (f *pdfFont) wrap(s string, size, width float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if f.width(candidate, size) <= width {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+
+		line = ""
+		for _, r := range word {
+			if line != "" && f.width(line+string(r), size) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			line += string(r)
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// pdfDocument lays out pages and collects the objects of
+// a PDF file. Objects are numbered from 1 in the order
+// they're added.
+type pdfDocument struct {
+	objects [][]byte
+	pages   []int
+
+	page   *bytes.Buffer
+	images []int // used on the page
+	y      float64
+}
+
+// The objects every document has, added by newPDFDocument.
+const (
+	pdfCatalog = iota + 1
+	pdfPages
+	pdfRegularFont
+	pdfBoldFont
+	pdfInfo
+)
+
+func newPDFDocument() *pdfDocument {
+	d := &pdfDocument{}
+	d.add("<< /Type /Catalog /Pages %d 0 R >>", pdfPages)
+	d.add("") // the page tree, once the pages are known
+	for _, f := range []*pdfFont{pdfRegular, pdfBold} {
+		d.add("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", f.name)
+	}
+	d.add("<< /Producer %s /CreationDate %s >>",
+		pdfString([]byte("go-dayone")),
+		pdfString([]byte(time.Now().UTC().Format("D:20060102150405Z"))))
+	return d
+}
+
+// add adds an object and returns its number.
+func (d *pdfDocument) add(format string, args ...interface{}) int {
+	d.objects = append(d.objects, []byte(fmt.Sprintf(format, args...)))
+	return len(d.objects)
+}
+
+// addStream adds a stream object, compressed unless it
+// already is, and returns its number.
+func (d *pdfDocument) addStream(dict string, data []byte, compress bool) int {
+	if compress {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		data = buf.Bytes()
+		dict += " /Filter /FlateDecode"
+	}
+
+	var obj bytes.Buffer
+	fmt.Fprintf(&obj, "<< %s /Length %d >>\nstream\n", dict, len(data))
+	obj.Write(data)
+	obj.WriteString("\nendstream")
+	d.objects = append(d.objects, obj.Bytes())
+	return len(d.objects)
+}
+
+// addJPEG adds an image object for JPEG data, which PDF
+// readers can decode themselves, and returns its number.
+func (d *pdfDocument) addJPEG(data []byte, cfg image.Config) int {
+	space := "/DeviceRGB"
+	switch cfg.ColorModel {
+	case color.GrayModel:
+		space = "/DeviceGray"
+	case color.CMYKModel:
+		// Adobe's CMYK JPEGs are stored inverted.
+		space = "/DeviceCMYK /Decode [1 0 1 0 1 0 1 0]"
+	}
+	dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode",
+		cfg.Width, cfg.Height, space)
+	return d.addStream(dict, data, false)
+}
+
+// newPage finishes the current page, if any, and starts
+// a new one at the top margin.
+func (d *pdfDocument) newPage() {
+	d.finishPage()
+	d.page = new(bytes.Buffer)
+	d.y = pdfPageHeight - pdfMargin
+}
+
+func (d *pdfDocument) finishPage() {
+	if d.page == nil {
+		return
+	}
+
+	// Number the pages after the cover.
+	if n := len(d.pages); n > 0 {
+		d.y = pdfMargin / 2
+		d.centered(strconv.Itoa(n), pdfRegular, 9)
+	}
+
+	contents := d.addStream("", d.page.Bytes(), true)
+
+	var xobjects bytes.Buffer
+	for _, id := range d.images {
+		fmt.Fprintf(&xobjects, " /Im%d %d 0 R", id, id)
+	}
+	page := d.add("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] "+
+		"/Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> /XObject <<%s >> >> /Contents %d 0 R >>",
+		pdfPages, pdfPageWidth, pdfPageHeight, pdfRegularFont, pdfBoldFont, xobjects.String(), contents)
+	d.pages = append(d.pages, page)
+
+	d.page, d.images = nil, nil
+}
+
+// need starts a new page unless there is at least height
+// left on this one.
+func (d *pdfDocument) need(height float64) {
+	if d.y-height < pdfMargin {
+		d.newPage()
+	}
+}
+
+// text draws s with its baseline at x, y.
+func (d *pdfDocument) text(s string, f *pdfFont, size, x, y, gray float64) {
+	fmt.Fprintf(d.page, "%s g BT /%s %s Tf %s %s Td %s Tj ET\n",
+		pdfNumber(gray), f.resource, pdfNumber(size), pdfNumber(x), pdfNumber(y), pdfString(f.encode(s)))
+}
+
+// paragraph draws s wrapped to the text width from the
+// current position down, starting new pages as needed.
+// gray is the gray level, 0 for black.
+func (d *pdfDocument) paragraph(s string, f *pdfFont, size, gray float64) {
+	leading := size * 1.35
+	for _, line := range f.wrap(s, size, pdfTextWidth) {
+		d.need(leading)
+		d.y -= leading
+		d.text(line, f, size, pdfMargin, d.y+size*0.3, gray)
+	}
+}
+
+// centered draws s centered on the page on one line.
+func (d *pdfDocument) centered(s string, f *pdfFont, size float64) {
+	d.y -= size * 1.35
+	d.text(s, f, size, (pdfPageWidth-f.width(s, size))/2, d.y, 0)
+}
+
+// image draws image object id w by h below the current
+// position.
+func (d *pdfDocument) image(id int, w, h float64) {
+	d.y -= h
+	fmt.Fprintf(d.page, "q %s 0 0 %s %s %s cm /Im%d Do Q\n",
+		pdfNumber(w), pdfNumber(h), pdfNumber(pdfMargin), pdfNumber(d.y), id)
+	d.images = append(d.images, id)
+}
+
+// WriteTo finishes the document and writes it to w.
+func (d *pdfDocument) WriteTo(w io.Writer) (int64, error) {
+	d.finishPage()
+
+	kids := make([]string, len(d.pages))
+	for i, id := range d.pages {
+		kids[i] = strconv.Itoa(id) + " 0 R"
+	}
+	d.objects[pdfPages-1] = []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>",
+		strings.Join(kids, " "), len(d.pages)))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, len(d.objects))
+	for i, obj := range d.objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n", i+1)
+		buf.Write(obj)
+		buf.WriteString("\nendobj\n")
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(d.objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(d.objects)+1, pdfCatalog, pdfInfo, xref)
+
+	return buf.WriteTo(w)
+}
+
+// pdfString formats b as a PDF literal string.
+func pdfString(b []byte) string {
+	var s bytes.Buffer
+	s.WriteByte('(')
+	for _, c := range b {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			s.WriteByte('\\')
+			s.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&s, "\\%03o", c)
+		default:
+			s.WriteByte(c)
+		}
+	}
+	s.WriteByte(')')
+	return s.String()
+}
+
+func pdfNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
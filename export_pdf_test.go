@@ -0,0 +1,136 @@
+package dayone
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var (
+	pdfXrefPattern   = regexp.MustCompile(`(?m)^(\d{10}) 00000 n $`)
+	pdfStreamPattern = regexp.MustCompile(`(?s)/FlateDecode /Length (\d+) >>\nstream\n`)
+)
+
+func exportPDF(t *testing.T, j *Journal, opts *PDFOptions) (int, string) {
+	var buf bytes.Buffer
+	n, err := j.ExportPDF(&buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf := buf.String()
+
+	if !strings.HasPrefix(pdf, "%PDF-1.4\n") || !strings.HasSuffix(pdf, "%%EOF\n") {
+		t.Fatalf("unexpected pdf:\n%s", pdf)
+	}
+
+	// Each object must be where the xref table says.
+	for i, m := range pdfXrefPattern.FindAllStringSubmatch(pdf, -1) {
+		off, _ := strconv.Atoi(m[1])
+		if !strings.HasPrefix(pdf[off:], strconv.Itoa(i+1)+" 0 obj\n") {
+			t.Errorf("object %d not at offset %d", i+1, off)
+		}
+	}
+	return n, pdf
+}
+
+// pdfText returns the decompressed page content streams.
+func pdfText(t *testing.T, pdf string) string {
+	var text bytes.Buffer
+	for _, m := range pdfStreamPattern.FindAllStringSubmatchIndex(pdf, -1) {
+		length, _ := strconv.Atoi(pdf[m[2]:m[3]])
+		zr, err := zlib.NewReader(strings.NewReader(pdf[m[1] : m[1]+length]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text.Write(b)
+	}
+	return text.String()
+}
+
+func TestExportPDF(t *testing.T) {
+	n, pdf := exportPDF(t, NewJournal("./test_journals/default"), &PDFOptions{Title: "Training (log)"})
+	if n != 2 {
+		t.Errorf("expected 2 entries, got %d", n)
+	}
+
+	if c := strings.Count(pdf, "/Type /Page "); c != 2 {
+		t.Errorf("expected cover and one page, got %d pages", c)
+	}
+	if !strings.Contains(pdf, "/Count 2 >>") {
+		t.Error("expected page count in page tree")
+	}
+	if strings.Count(pdf, "/Filter /DCTDecode") != 1 {
+		t.Error("expected one embedded jpeg")
+	}
+
+	text := pdfText(t, pdf)
+	for _, expected := range []string{
+		`(Training \(log\)) Tj`,
+		`(September 23, 2014) Tj`,
+		`(2 entries) Tj`,
+		`(Tuesday, September 23, 2014 8:52 PM \267 Starred) Tj`,
+		`/F2 16 Tf 72 `,
+		`(title line) Tj`,
+		`(body line) Tj`,
+		`(#bjj #fitness) Tj`,
+		`(199 Address Ln, `,
+		`/Im`,
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("expected %q in:\n%s", expected, text)
+		}
+	}
+}
+
+func TestExportPDFPageBreaks(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanup()
+
+	long := "# Long\n\n" + strings.Repeat("All work and no play makes Jack a dull boy. ", 600)
+	if err := j.Write(&Entry{EntryText: long}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, pdf := exportPDF(t, j, nil)
+	if c := strings.Count(pdf, "/Type /Page "); c < 4 {
+		t.Errorf("expected the entry to run over several pages, got %d pages", c)
+	}
+	if text := pdfText(t, pdf); !strings.Contains(text, "(3) Tj") {
+		t.Error("expected page numbers")
+	}
+}
+
+func TestPDFFontWrap(t *testing.T) {
+	if w := pdfRegular.width("Hello", 10); w != 22.78 {
+		t.Errorf("unexpected width: %v", w)
+	}
+
+	lines := pdfRegular.wrap("the quick brown fox jumps", 10, 60)
+	for _, l := range lines {
+		if pdfRegular.width(l, 10) > 60 {
+			t.Errorf("line too wide: %q", l)
+		}
+	}
+	if strings.Join(lines, " ") != "the quick brown fox jumps" {
+		t.Errorf("unexpected lines: %q", lines)
+	}
+
+	lines = pdfRegular.wrap(strings.Repeat("m", 20), 10, 50)
+	if len(lines) != 4 {
+		t.Errorf("expected long word broken over 4 lines, got %q", lines)
+	}
+}
+
+func TestPDFString(t *testing.T) {
+	if s := pdfString(pdfRegular.encode("a (b) \\ é – ☃")); s != `(a \(b\) \\ \351 \226 ?)` {
+		t.Errorf("unexpected string: %s", s)
+	}
+}
@@ -0,0 +1,52 @@
+package dayone
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestExportEntriesSorted(t *testing.T) {
+	j := NewJournal("./test_journals/default")
+
+	entries, err := j.exportEntries(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[1].CreationDate.Before(entries[0].CreationDate) {
+		t.Error("expected entries oldest first")
+	}
+}
+
+func TestExportPath(t *testing.T) {
+	e, err := NewJournal("./test_journals/default").ReadEntry("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := filepath.Join("2014", "09", "2014-09-23-FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if exportPath(e) != expected {
+		t.Errorf("unexpected path %q", exportPath(e))
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		text     string
+		n        int
+		expected string
+	}{
+		{"#title line\n\nbody line", 200, "body line"},
+		{"title only", 200, ""},
+		{"title\none two  three\nfour", 13, "one two three…"},
+		{"title\none two three four", 10, "one two…"},
+	}
+
+	for _, test := range tests {
+		if actual := excerpt(test.text, test.n); actual != test.expected {
+			t.Errorf("excerpt(%q, %d) = %q, want %q", test.text, test.n, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package dayone
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	hashtagPattern    = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#/])#([\p{L}\p{N}_][\p{L}\p{N}_-]*)`)
+	codeSpanPattern   = regexp.MustCompile("(`+).*?(?:`+|$)")
+	urlPattern        = regexp.MustCompile(`(?i)\b(?:[a-z][a-z0-9+.-]*://|www\.)\S+`)
+	headingPattern    = regexp.MustCompile(`^ {0,3}#+`)
+	codeFencePrefixes = []string{"```", "~~~"}
+)
+
+// ExtractHashtags returns the inline #hashtags found in text,
+// without the leading '#', in the order they first appear.
+// Duplicates are removed case-insensitively.
+//
+// Markdown headings (any line starting with '#', such as
+// "#title line"), code spans, fenced code blocks and URLs
+// are ignored. Tags made up only of digits (e.g. "#1")
+// are not considered hashtags.
+func ExtractHashtags(text string) []string {
+	var tags []string
+	inFence := false
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if isCodeFence(trimmed) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		line = headingPattern.ReplaceAllString(line, "")
+		line = codeSpanPattern.ReplaceAllString(line, " ")
+		line = urlPattern.ReplaceAllString(line, " ")
+
+		for _, m := range hashtagPattern.FindAllStringSubmatch(line, -1) {
+			tag := strings.TrimRight(m[1], "-")
+			if isNumeric(tag) || containsFold(tags, tag) {
+				continue
+			}
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+func isCodeFence(line string) bool {
+	for _, p := range codeFencePrefixes {
+		if strings.HasPrefix(line, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// InlineTags returns the #hashtags found in the entry text.
+func (e *Entry) InlineTags() []string {
+	return ExtractHashtags(e.EntryText)
+}
+
+// MergeInlineTags adds any inline #hashtags that are missing
+// from Tags. Tags are compared case-insensitively. It reports
+// whether Tags was changed.
+func (e *Entry) MergeInlineTags() bool {
+	changed := false
+	for _, tag := range e.InlineTags() {
+		if !containsFold(e.Tags, tag) {
+			e.Tags = append(e.Tags, tag)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// TagMismatch describes an entry whose inline #hashtags
+// and Tags disagree.
+type TagMismatch struct {
+	UUID       string
+	InlineOnly []string // hashtags in the text but not in Tags
+	TagsOnly   []string // Tags that don't appear in the text
+}
+
+// TagMismatches reads every entry in the journal and reports
+// the ones whose inline #hashtags and Tags disagree.
+func (j *Journal) TagMismatches() ([]TagMismatch, error) {
+	var mismatches []TagMismatch
+
+	err := j.Read(func(e *Entry, err error) error {
+		if e == nil {
+			return err
+		}
+
+		inline := e.InlineTags()
+		m := TagMismatch{UUID: e.UUID()}
+		for _, tag := range inline {
+			if !containsFold(e.Tags, tag) {
+				m.InlineOnly = append(m.InlineOnly, tag)
+			}
+		}
+		for _, tag := range e.Tags {
+			if !containsFold(inline, tag) {
+				m.TagsOnly = append(m.TagsOnly, tag)
+			}
+		}
+
+		if len(m.InlineOnly) > 0 || len(m.TagsOnly) > 0 {
+			mismatches = append(mismatches, m)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mismatches, nil
+}
@@ -0,0 +1,96 @@
+package dayone
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	text := "#title line\n\n" +
+		"Rolled at #bjj tonight, then #Fitness and #bjj again.\n" +
+		"See http://example.com/#anchor and `#notatag` or issue #42.\n" +
+		"```\n#code\n```\n" +
+		"Ending with #jiu-jitsu."
+
+	tags := ExtractHashtags(text)
+	expected := []string{"bjj", "Fitness", "jiu-jitsu"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("actual: %v, expected: %v", tags, expected)
+	}
+}
+
+func TestExtractHashtagsInHeading(t *testing.T) {
+	tags := ExtractHashtags("## Day at the #beach")
+	if len(tags) != 1 || tags[0] != "beach" {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+}
+
+func TestMergeInlineTags(t *testing.T) {
+	e := &Entry{
+		EntryText: "went to #BJJ and #yoga",
+		Tags:      []string{"bjj"},
+	}
+
+	if !e.MergeInlineTags() {
+		t.Error("expected tags to change")
+	}
+
+	if !reflect.DeepEqual(e.Tags, []string{"bjj", "yoga"}) {
+		t.Errorf("unexpected tags: %v", e.Tags)
+	}
+
+	if e.MergeInlineTags() {
+		t.Error("expected no change on second merge")
+	}
+}
+
+func TestTagMismatches(t *testing.T) {
+	j := NewJournal("./test_journals/default")
+
+	mismatches, err := j.TagMismatches()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mismatches) != 2 {
+		t.Fatalf("expected 2 mismatches, got %v", len(mismatches))
+	}
+
+	m := mismatches[0]
+	if m.UUID != "871D0F435D7B469C9429CD441A9E74B5" {
+		t.Error("mismatch uuid")
+	}
+	if len(m.InlineOnly) != 0 {
+		t.Error("expected no inline only tags")
+	}
+	if !reflect.DeepEqual(m.TagsOnly, []string{"bjj", "fitness"}) {
+		t.Errorf("unexpected tags only: %v", m.TagsOnly)
+	}
+}
+
+func TestWriteSyncsInlineTags(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	j.SyncInlineTags = true
+
+	e, err := j.ReadEntry("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.EntryText += " #judo"
+	if err := j.Write(e); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err = j.ReadEntry("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(e.Tags, []string{"bjj", "fitness", "judo"}) {
+		t.Errorf("unexpected tags: %v", e.Tags)
+	}
+}
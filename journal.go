@@ -8,18 +8,41 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 const entryExt = ".doentry"
 const photoExt = ".jpg"
 
+// tempSuffix is part of the name of the temp files used while
+// writing. Temp files are hidden and never end in entryExt.
+const tempSuffix = ".tmp"
+
 // ErrStopRead is an error you can return from a
 // ReadFunc to stop reading journal entries.
 var ErrStopRead = errors.New("stop reading")
 
+// ErrConflict is returned when writing an entry whose file
+// was changed or removed since the entry was read.
+var ErrConflict = errors.New("entry changed since it was read")
+
 // Journal is the top-level type for reading Day One journal files.
 type Journal struct {
 	dir string
+
+	// SyncInlineTags merges the #hashtags found in an
+	// entry's text into its Tags when the entry is written.
+	SyncInlineTags bool
+
+	// TrustFilenameUUID makes entries take their uuid from
+	// the entry file name rather than the plist UUID key
+	// when the two disagree.
+	TrustFilenameUUID bool
+
+	// KeepVersions records the prior and new versions of
+	// each entry and photo in a hidden dir in the journal
+	// whenever they are written, deleted or restored.
+	KeepVersions bool
 }
 
 // NewJournal creates a new Journal for the
@@ -38,24 +61,219 @@ func (j *Journal) getPhotosDir() string {
 	return filepath.Join(j.dir, "photos")
 }
 
-/*
-func (j Journal) Write(e *Entry) error {
-	if e.id == "" {
-		// TODO: Add support for writing new entries.
-		//       - Ensure unique on FS (probably by just not allowing overwrite file on write)
-		//       - Write to file
-		return errors.New("cannot write new entries: not supported yet")
+// Write saves the entry to the journal, replacing any
+// existing entry with the same uuid. Entries without a
+// uuid are assigned a new one, along with a CreationDate
+// of now if they don't have one. Entries that don't
+// validate are refused with a *ValidationError.
+//
+// If the entry was read from the journal and its file has
+// been modified or removed since, Write returns ErrConflict
+// rather than overwriting someone else's changes.
+func (j *Journal) Write(e *Entry) error {
+	unlock, err := j.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return j.writeEntry(e)
+}
+
+// writeEntry is Write for a caller holding the lock.
+func (j *Journal) writeEntry(e *Entry) error {
+	if j.SyncInlineTags {
+		e.MergeInlineTags()
+	}
+
+	return j.save(e)
+}
+
+// save is writeEntry without merging inline tags: it assigns
+// a uuid if needed, validates and writes the entry. The
+// caller must hold the lock.
+func (j *Journal) save(e *Entry) error {
+	if e.uuid == "" {
+		e.uuid = newUUID()
+		if e.CreationDate.IsZero() {
+			e.CreationDate = time.Now().UTC()
+		}
+	}
+
+	if err := e.validateWrite(); err != nil {
+		return err
+	}
+
+	return j.write(e)
+}
+
+// write saves a validated entry. The caller must hold the lock.
+func (j *Journal) write(e *Entry) error {
+	if err := j.checkConflict(e); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(j.getEntriesDir(), 0755); err != nil {
+		return errgo.Mask(err)
+	}
+
+	path := filepath.Join(j.getEntriesDir(), e.uuid+entryExt)
+	err := j.versioned(e.uuid, func() error {
+		return writeFileAtomic(path, e.encode)
+	})
+	if err != nil {
+		return err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return errgo.Mask(err)
+	}
+	e.setStat(fi)
+
+	return nil
+}
+
+// checkConflict returns ErrConflict if the entry file has
+// changed since e was read. Entries that weren't read from
+// the journal never conflict.
+func (j *Journal) checkConflict(e *Entry) error {
+	if e.modTime.IsZero() {
+		return nil
+	}
+
+	fi, err := j.EntryStat(e.uuid)
+	if os.IsNotExist(err) {
+		return ErrConflict
+	} else if err != nil {
+		return err
+	}
+
+	if !fi.ModTime().Equal(e.modTime) || fi.Size() != e.size {
+		return ErrConflict
+	}
+
+	return nil
+}
+
+// WritePhoto saves the photo read from r as the photo for
+// the entry uuid, replacing any existing photo.
+func (j *Journal) WritePhoto(uuid string, r io.Reader) error {
+	unlock, err := j.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return j.writePhoto(uuid, r)
+}
+
+// writePhoto is WritePhoto for a caller holding the lock.
+func (j *Journal) writePhoto(uuid string, r io.Reader) error {
+	if !uuidPattern.MatchString(uuid) {
+		return errors.New("invalid uuid " + uuid)
+	}
+
+	if err := os.MkdirAll(j.getPhotosDir(), 0755); err != nil {
+		return errgo.Mask(err)
+	}
+
+	path := filepath.Join(j.getPhotosDir(), uuid+photoExt)
+	return j.versioned(uuid, func() error {
+		return writeFileAtomic(path, func(w io.Writer) error {
+			_, err := io.Copy(w, r)
+			return err
+		})
+	})
+}
+
+// writeFileAtomic writes the file at path by passing a temp
+// file in the same dir to fn, syncing it to disk and renaming
+// it into place once fn succeeds. Readers (and sync clients
+// like Dropbox) never see a partially written file.
+func writeFileAtomic(path string, fn func(w io.Writer) error) error {
+	dir := filepath.Dir(path)
+
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(path)+tempSuffix)
+	if err != nil {
+		return errgo.Mask(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if err := fn(f); err != nil {
+		return errgo.Mask(err)
+	}
+
+	if err := f.Chmod(0644); err != nil {
+		return errgo.Mask(err)
+	}
+
+	if err := f.Sync(); err != nil {
+		return errgo.Mask(err)
+	}
+
+	if err := f.Close(); err != nil {
+		return errgo.Mask(err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return errgo.Mask(err)
+	}
+
+	syncDir(dir)
+	return nil
+}
+
+// syncDir flushes the directory entry for a rename to disk.
+// This is best effort since not every platform allows
+// syncing a directory.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
 	}
+	d.Sync()
+	d.Close()
+}
 
-	if err := e.validate(); err != nil {
+// Delete removes the entry with the specified uuid
+// along with its photo, if it has one.
+func (j *Journal) Delete(uuid string) error {
+	unlock, err := j.lock()
+	if err != nil {
 		return err
 	}
+	defer unlock()
+
+	return j.delete(uuid)
+}
+
+// delete removes an entry and its photo. The caller must hold the lock.
+func (j *Journal) delete(uuid string) error {
+	return j.versioned(uuid, func() error {
+		return j.removeEntryFiles(uuid)
+	})
+}
+
+func (j *Journal) removeEntryFiles(uuid string) error {
+	err := os.Remove(filepath.Join(j.getEntriesDir(), uuid+entryExt))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return err
+		} else {
+			return errgo.Mask(err)
+		}
+	}
 
-	// TODO: Overwrite file in journal
+	err = os.Remove(filepath.Join(j.getPhotosDir(), uuid+photoExt))
+	if err != nil && !os.IsNotExist(err) {
+		return errgo.Mask(err)
+	}
 
+	syncDir(j.getEntriesDir())
 	return nil
 }
-*/
 
 // PhotoStat returns the result of os.Stat() for the
 // photo associated with the entry uuid.
@@ -110,8 +328,16 @@ func (j *Journal) EntryStat(uuid string) (os.FileInfo, error) {
 
 // ReadEntry reads the entry with the specified id.
 func (j *Journal) ReadEntry(uuid string) (*Entry, error) {
-	path := filepath.Join(j.getEntriesDir(), uuid+entryExt)
+	e, err := readEntryFile(filepath.Join(j.getEntriesDir(), uuid+entryExt))
+	if err != nil {
+		return nil, err
+	}
+
+	j.resolveUUID(e, uuid)
+	return e, nil
+}
 
+func readEntryFile(path string) (*Entry, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -129,6 +355,12 @@ func (j *Journal) ReadEntry(uuid string) (*Entry, error) {
 		return nil, err
 	}
 
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	e.setStat(fi)
+
 	return e, nil
 }
 
@@ -139,20 +371,46 @@ type ReadFunc func(e *Entry, err error) error
 // fn with each entry found. Errors returned by fn
 // are returned by Read. fn can return StopError
 // to halt enumeration at any point.
+//
+// Entries whose file name and plist UUID disagree, that
+// are missing a UUID, or whose UUID was already read from
+// another file are passed to fn along with a
+// *UUIDMismatchError, *MissingUUIDError or
+// *DuplicateUUIDError respectively.
 func (j *Journal) Read(fn ReadFunc) error {
+	uuids, err := j.entryUUIDs()
+	if err != nil {
+		return err
+	}
 
-	var err error
-	var e *Entry
+	seen := make(map[string]string)
+	for _, uuid := range uuids {
+		e, err := j.readChecked(uuid, seen)
+		err = fn(e, err)
+
+		if err == ErrStopRead {
+			return nil
+		} else if err != nil {
+			return errgo.NoteMask(err, "file: "+uuid+entryExt)
+		}
+	}
 
+	return nil
+}
+
+// entryUUIDs returns the uuids of all the entry files
+// in the journal, sorted by file name.
+func (j *Journal) entryUUIDs() ([]string, error) {
 	files, err := ioutil.ReadDir(j.getEntriesDir())
 	if err != nil {
 		if os.IsNotExist(err) {
-			return err
+			return nil, err
 		} else {
-			return errgo.Mask(err)
+			return nil, errgo.Mask(err)
 		}
 	}
 
+	var uuids []string
 	for _, f := range files {
 		if f.IsDir() {
 			continue
@@ -162,24 +420,60 @@ func (j *Journal) Read(fn ReadFunc) error {
 			continue
 		}
 
-		uuid := strings.TrimSuffix(filepath.Base(f.Name()), filepath.Ext(f.Name()))
-		e, err = j.ReadEntry(uuid)
-		err = fn(e, err)
+		uuids = append(uuids, strings.TrimSuffix(filepath.Base(f.Name()), filepath.Ext(f.Name())))
+	}
 
-		if err == ErrStopRead {
-			return nil
-		} else if err != nil {
-			return errgo.NoteMask(err, "file: "+f.Name())
+	return uuids, nil
+}
+
+// photoUUIDs returns the uuids of all the photo files
+// in the journal, sorted by file name.
+func (j *Journal) photoUUIDs() ([]string, error) {
+	files, err := ioutil.ReadDir(j.getPhotosDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		} else {
+			return nil, errgo.Mask(err)
 		}
 	}
 
-	return nil
+	var uuids []string
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+
+		if !isPhotoFile(f.Name()) {
+			continue
+		}
+
+		uuids = append(uuids, strings.TrimSuffix(filepath.Base(f.Name()), filepath.Ext(f.Name())))
+	}
+
+	return uuids, nil
 }
 
 func isEntryFile(name string) bool {
+	if isTempFile(name) || isConflictedCopy(name) {
+		return false
+	}
+
 	if strings.EqualFold(filepath.Ext(name), entryExt) {
 		return true
 	}
 
 	return false
 }
+
+func isPhotoFile(name string) bool {
+	if isTempFile(name) || isConflictedCopy(name) {
+		return false
+	}
+
+	return strings.EqualFold(filepath.Ext(name), photoExt)
+}
+
+func isTempFile(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.Contains(name, tempSuffix)
+}
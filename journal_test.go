@@ -2,7 +2,9 @@ package dayone
 
 import (
 	"errors"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -242,3 +244,207 @@ func TestReadingEmptyEntriesDir(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// copyJournal copies the journal at dir into a temp dir
+// so tests can write to it.
+func copyJournal(t *testing.T, dir string) (*Journal, func()) {
+	tmp, err := ioutil.TempDir("", "dayone")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(tmp, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(target, b, 0644)
+	})
+	if err != nil {
+		os.RemoveAll(tmp)
+		t.Fatal(err)
+	}
+
+	return NewJournal(tmp), func() { os.RemoveAll(tmp) }
+}
+
+func TestWriteNewEntry(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanup()
+
+	e := &Entry{EntryText: "hello"}
+	if err := j.Write(e); err != nil {
+		t.Fatal(err)
+	}
+
+	if e.UUID() == "" {
+		t.Fatal("expected uuid to be assigned")
+	}
+
+	read, err := j.ReadEntry(e.UUID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if read.EntryText != "hello" {
+		t.Error("entry text")
+	}
+}
+
+func TestWriteOverwritesEntry(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	e, err := j.ReadEntry("871D0F435D7B469C9429CD441A9E74B5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.Starred = false
+	if err := j.Write(e); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err = j.ReadEntry("871D0F435D7B469C9429CD441A9E74B5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Starred {
+		t.Error("expected entry to be unstarred")
+	}
+}
+
+func TestWritePhoto(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanup()
+
+	if err := j.WritePhoto("FF755C6D7D9B4A5FBC4E41C07D622C65", strings.NewReader("jpeg")); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := j.OpenPhoto("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "jpeg" {
+		t.Error("photo contents")
+	}
+}
+
+func TestWriteLeavesNoTempFiles(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanup()
+
+	if err := j.Write(&Entry{EntryText: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ioutil.ReadDir(j.getEntriesDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range files {
+		if isTempFile(f.Name()) {
+			t.Errorf("temp file left behind: %s", f.Name())
+		}
+	}
+}
+
+func TestReadIgnoresTempFiles(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanup()
+
+	// A temp file left behind by a crashed write.
+	path := filepath.Join(j.getEntriesDir(), ".FF755C6D7D9B4A5FBC4E41C07D622C65.doentry.tmp123")
+	if err := ioutil.WriteFile(path, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	err := j.Read(func(e *Entry, err error) error {
+		count++
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 0 {
+		t.Error("read temp file as an entry")
+	}
+}
+
+func TestWriteConflict(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	e1, err := j.ReadEntry("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e2, err := j.ReadEntry("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e1.EntryText = "first writer"
+	if err := j.Write(e1); err != nil {
+		t.Fatal(err)
+	}
+
+	e2.EntryText = "second writer"
+	if err := j.Write(e2); err != ErrConflict {
+		t.Errorf("expected conflict, got %v", err)
+	}
+
+	// The first writer can keep writing its own changes.
+	e1.EntryText = "first writer again"
+	if err := j.Write(e1); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeleteEntry(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	if err := j.Delete("871D0F435D7B469C9429CD441A9E74B5"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := j.EntryStat("871D0F435D7B469C9429CD441A9E74B5"); !os.IsNotExist(err) {
+		t.Error("expected entry to be removed")
+	}
+
+	if _, err := j.PhotoStat("871D0F435D7B469C9429CD441A9E74B5"); !os.IsNotExist(err) {
+		t.Error("expected photo to be removed")
+	}
+
+	if err := j.Delete("871D0F435D7B469C9429CD441A9E74B5"); !os.IsNotExist(err) {
+		t.Errorf("expected an os not exist error, got %v", err)
+	}
+}
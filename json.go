@@ -0,0 +1,393 @@
+package dayone
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"github.com/juju/errgo"
+	"io"
+	"time"
+)
+
+// The JSON forms of the entry types. Field names are snake
+// case and stable; empty fields are left out, except for the
+// ones every entry has.
+
+type entryJSON struct {
+	UUID            string    `json:"uuid"`
+	CreationDate    time.Time `json:"creation_date"`
+	TimeZone        string    `json:"time_zone,omitempty"`
+	EntryText       string    `json:"text"`
+	Tags            []string  `json:"tags"`
+	Starred         bool      `json:"starred"`
+	Activity        string    `json:"activity,omitempty"`
+	StepCount       uint64    `json:"step_count,omitempty"`
+	IgnoreStepCount bool      `json:"ignore_step_count,omitempty"`
+	PublishURL      string    `json:"publish_url,omitempty"`
+	Location        *Location `json:"location,omitempty"`
+	Weather         *Weather  `json:"weather,omitempty"`
+	Music           *Music    `json:"music,omitempty"`
+	Creator         *Creator  `json:"creator,omitempty"`
+}
+
+type creatorJSON struct {
+	DeviceAgent    string     `json:"device_agent,omitempty"`
+	GenerationDate *time.Time `json:"generation_date,omitempty"`
+	HostName       string     `json:"host_name,omitempty"`
+	OSAgent        string     `json:"os_agent,omitempty"`
+	SoftwareAgent  string     `json:"software_agent,omitempty"`
+}
+
+type locationJSON struct {
+	AdministrativeArea string  `json:"administrative_area,omitempty"`
+	Country            string  `json:"country,omitempty"`
+	Locality           string  `json:"locality,omitempty"`
+	PlaceName          string  `json:"place_name,omitempty"`
+	FoursquareID       string  `json:"foursquare_id,omitempty"`
+	Latitude           float64 `json:"latitude"`
+	Longitude          float64 `json:"longitude"`
+	Region             *Region `json:"region,omitempty"`
+}
+
+type regionJSON struct {
+	Center *Coordinate `json:"center,omitempty"`
+	Radius float64     `json:"radius,omitempty"`
+}
+
+type coordinateJSON struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+type weatherJSON struct {
+	Celsius          string     `json:"celsius,omitempty"`
+	Fahrenheit       string     `json:"fahrenheit,omitempty"`
+	Description      string     `json:"description,omitempty"`
+	IconName         string     `json:"icon_name,omitempty"`
+	PressureMB       float64    `json:"pressure_mb,omitempty"`
+	RelativeHumidity float64    `json:"relative_humidity,omitempty"`
+	Service          string     `json:"service,omitempty"`
+	SunriseDate      *time.Time `json:"sunrise_date,omitempty"`
+	SunsetDate       *time.Time `json:"sunset_date,omitempty"`
+	VisibilityKM     float64    `json:"visibility_km,omitempty"`
+	WindBearing      uint64     `json:"wind_bearing,omitempty"`
+	WindChillCelsius int64      `json:"wind_chill_celsius,omitempty"`
+	WindSpeedKPH     float64    `json:"wind_speed_kph,omitempty"`
+}
+
+type musicJSON struct {
+	Album     string `json:"album,omitempty"`
+	Artist    string `json:"artist,omitempty"`
+	Track     string `json:"track,omitempty"`
+	AlbumYear string `json:"album_year,omitempty"`
+}
+
+// MarshalJSON encodes the entry, including its uuid.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	tags := e.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return json.Marshal(entryJSON{
+		UUID:            e.uuid,
+		CreationDate:    e.CreationDate.UTC(),
+		TimeZone:        e.TimeZone,
+		EntryText:       e.EntryText,
+		Tags:            tags,
+		Starred:         e.Starred,
+		Activity:        e.Activity,
+		StepCount:       e.StepCount,
+		IgnoreStepCount: e.IgnoreStepCount,
+		PublishURL:      e.PublishURL,
+		Location:        e.Location,
+		Weather:         e.Weather,
+		Music:           e.Music,
+		Creator:         e.Creator,
+	})
+}
+
+// UnmarshalJSON decodes an entry encoded by MarshalJSON.
+// Unknown keys are an error, as they are for plists.
+func (e *Entry) UnmarshalJSON(b []byte) error {
+	var v entryJSON
+	if err := decodeStrict(b, &v); err != nil {
+		return err
+	}
+
+	if len(v.Tags) == 0 {
+		v.Tags = nil
+	}
+
+	*e = Entry{
+		uuid:            v.UUID,
+		CreationDate:    v.CreationDate.UTC(),
+		TimeZone:        v.TimeZone,
+		EntryText:       v.EntryText,
+		Tags:            v.Tags,
+		Starred:         v.Starred,
+		Activity:        v.Activity,
+		StepCount:       v.StepCount,
+		IgnoreStepCount: v.IgnoreStepCount,
+		PublishURL:      v.PublishURL,
+		Location:        v.Location,
+		Weather:         v.Weather,
+		Music:           v.Music,
+		Creator:         v.Creator,
+	}
+	return nil
+}
+
+// MarshalJSON encodes the creator.
+func (c Creator) MarshalJSON() ([]byte, error) {
+	return json.Marshal(creatorJSON{
+		DeviceAgent:    c.DeviceAgent,
+		GenerationDate: optionalTime(c.GenerationDate),
+		HostName:       c.HostName,
+		OSAgent:        c.OSAgent,
+		SoftwareAgent:  c.SoftwareAgent,
+	})
+}
+
+// UnmarshalJSON decodes a creator encoded by MarshalJSON.
+func (c *Creator) UnmarshalJSON(b []byte) error {
+	var v creatorJSON
+	if err := decodeStrict(b, &v); err != nil {
+		return err
+	}
+
+	*c = Creator{
+		DeviceAgent:    v.DeviceAgent,
+		GenerationDate: timeOrZero(v.GenerationDate),
+		HostName:       v.HostName,
+		OSAgent:        v.OSAgent,
+		SoftwareAgent:  v.SoftwareAgent,
+	}
+	return nil
+}
+
+// MarshalJSON encodes the location with its
+// coordinate as top level latitude and longitude.
+func (l Location) MarshalJSON() ([]byte, error) {
+	return json.Marshal(locationJSON{
+		AdministrativeArea: l.AdministrativeArea,
+		Country:            l.Country,
+		Locality:           l.Locality,
+		PlaceName:          l.PlaceName,
+		FoursquareID:       l.FoursquareID,
+		Latitude:           l.Latitude,
+		Longitude:          l.Longitude,
+		Region:             l.Region,
+	})
+}
+
+// UnmarshalJSON decodes a location encoded by MarshalJSON.
+func (l *Location) UnmarshalJSON(b []byte) error {
+	var v locationJSON
+	if err := decodeStrict(b, &v); err != nil {
+		return err
+	}
+
+	*l = Location{
+		AdministrativeArea: v.AdministrativeArea,
+		Country:            v.Country,
+		Locality:           v.Locality,
+		PlaceName:          v.PlaceName,
+		FoursquareID:       v.FoursquareID,
+		Region:             v.Region,
+		Coordinate:         Coordinate{v.Latitude, v.Longitude},
+	}
+	return nil
+}
+
+// MarshalJSON encodes the region.
+func (r Region) MarshalJSON() ([]byte, error) {
+	return json.Marshal(regionJSON{Center: r.Center, Radius: r.Radius})
+}
+
+// UnmarshalJSON decodes a region encoded by MarshalJSON.
+func (r *Region) UnmarshalJSON(b []byte) error {
+	var v regionJSON
+	if err := decodeStrict(b, &v); err != nil {
+		return err
+	}
+
+	*r = Region{Center: v.Center, Radius: v.Radius}
+	return nil
+}
+
+// MarshalJSON encodes the coordinate.
+func (c Coordinate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(coordinateJSON{Latitude: c.Latitude, Longitude: c.Longitude})
+}
+
+// UnmarshalJSON decodes a coordinate encoded by MarshalJSON.
+func (c *Coordinate) UnmarshalJSON(b []byte) error {
+	var v coordinateJSON
+	if err := decodeStrict(b, &v); err != nil {
+		return err
+	}
+
+	*c = Coordinate{Latitude: v.Latitude, Longitude: v.Longitude}
+	return nil
+}
+
+// MarshalJSON encodes the weather.
+func (w Weather) MarshalJSON() ([]byte, error) {
+	return json.Marshal(weatherJSON{
+		Celsius:          w.Celsius,
+		Fahrenheit:       w.Fahrenheit,
+		Description:      w.Description,
+		IconName:         w.IconName,
+		PressureMB:       w.PressureMB,
+		RelativeHumidity: w.RelativeHumidity,
+		Service:          w.Service,
+		SunriseDate:      optionalTime(w.SunriseDate),
+		SunsetDate:       optionalTime(w.SunsetDate),
+		VisibilityKM:     w.VisibilityKM,
+		WindBearing:      w.WindBearing,
+		WindChillCelsius: w.WindChillCelsius,
+		WindSpeedKPH:     w.WindSpeedKPH,
+	})
+}
+
+// UnmarshalJSON decodes weather encoded by MarshalJSON.
+func (w *Weather) UnmarshalJSON(b []byte) error {
+	var v weatherJSON
+	if err := decodeStrict(b, &v); err != nil {
+		return err
+	}
+
+	*w = Weather{
+		Celsius:          v.Celsius,
+		Fahrenheit:       v.Fahrenheit,
+		Description:      v.Description,
+		IconName:         v.IconName,
+		PressureMB:       v.PressureMB,
+		RelativeHumidity: v.RelativeHumidity,
+		Service:          v.Service,
+		SunriseDate:      timeOrZero(v.SunriseDate),
+		SunsetDate:       timeOrZero(v.SunsetDate),
+		VisibilityKM:     v.VisibilityKM,
+		WindBearing:      v.WindBearing,
+		WindChillCelsius: v.WindChillCelsius,
+		WindSpeedKPH:     v.WindSpeedKPH,
+	}
+	return nil
+}
+
+// MarshalJSON encodes the music.
+func (m Music) MarshalJSON() ([]byte, error) {
+	return json.Marshal(musicJSON{
+		Album:     m.Album,
+		Artist:    m.Artist,
+		Track:     m.Track,
+		AlbumYear: m.AlbumYear,
+	})
+}
+
+// UnmarshalJSON decodes music encoded by MarshalJSON.
+func (m *Music) UnmarshalJSON(b []byte) error {
+	var v musicJSON
+	if err := decodeStrict(b, &v); err != nil {
+		return err
+	}
+
+	*m = Music{
+		Album:     v.Album,
+		Artist:    v.Artist,
+		Track:     v.Track,
+		AlbumYear: v.AlbumYear,
+	}
+	return nil
+}
+
+func decodeStrict(b []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// optionalTime returns nil for the zero time so
+// it's left out, otherwise the time in UTC.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
+
+// ExportJSONLines writes each entry matching filter to w as
+// one line of JSON, oldest first. A nil filter exports every
+// entry. Photos aren't included. It returns the number of
+// entries written.
+func (j *Journal) ExportJSONLines(w io.Writer, filter FilterFunc) (int, error) {
+	entries, err := j.exportEntries(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	for i, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			// Flush the lines already encoded so the count
+			// matches what reached w.
+			bw.Flush()
+			return i, err
+		}
+	}
+	return len(entries), bw.Flush()
+}
+
+// maxJSONLine is the longest line ImportJSONLines accepts.
+const maxJSONLine = 64 << 20
+
+// ImportJSONLines reads entries written by ExportJSONLines
+// from r and writes each one to the journal, replacing any
+// entry with the same uuid. Blank lines are skipped. It stops
+// at the first line that can't be decoded or written and
+// returns the number of entries written before it.
+func (j *Journal) ImportJSONLines(r io.Reader) (int, error) {
+	unlock, err := j.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxJSONLine)
+
+	n, line := 0, 0
+	for scanner.Scan() {
+		line++
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+
+		e := &Entry{}
+		if err := json.Unmarshal(b, e); err != nil {
+			return n, errgo.Notef(err, "line %d", line)
+		}
+		if err := j.writeEntry(e); err != nil {
+			return n, errgo.Notef(err, "line %d", line)
+		}
+		n++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return n, errgo.Mask(err)
+	}
+	return n, nil
+}
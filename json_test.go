@@ -0,0 +1,128 @@
+package dayone
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEntryJSONRoundTrips(t *testing.T) {
+	e, err := NewJournal("./test_journals/default").ReadEntry("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.forgetStat()
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Entry
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(e, &decoded) {
+		t.Errorf("entry changed in round trip: %v", DiffEntries(e, &decoded))
+	}
+	if decoded.UUID() != "FF755C6D7D9B4A5FBC4E41C07D622C65" {
+		t.Error("expected uuid to be decoded")
+	}
+}
+
+func TestEntryJSONFields(t *testing.T) {
+	e := &Entry{
+		uuid:         "FF755C6D7D9B4A5FBC4E41C07D622C65",
+		CreationDate: time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC),
+		EntryText:    "hi",
+		Location:     &Location{Coordinate: Coordinate{1.5, -2}},
+		Weather:      &Weather{Celsius: "20"},
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"uuid":"FF755C6D7D9B4A5FBC4E41C07D622C65",` +
+		`"creation_date":"2015-01-02T03:04:05Z",` +
+		`"text":"hi","tags":[],"starred":false,` +
+		`"location":{"latitude":1.5,"longitude":-2},` +
+		`"weather":{"celsius":"20"}}`
+	if string(b) != expected {
+		t.Errorf("unexpected json:\n%s", b)
+	}
+}
+
+func TestEntryJSONUnknownKey(t *testing.T) {
+	var e Entry
+	err := json.Unmarshal([]byte(`{"uuid":"FF755C6D7D9B4A5FBC4E41C07D622C65","mood":"happy"}`), &e)
+	if err == nil || !strings.Contains(err.Error(), "mood") {
+		t.Errorf("expected unknown key error, got %v", err)
+	}
+
+	err = json.Unmarshal([]byte(`{"location":{"altitude":3}}`), &e)
+	if err == nil {
+		t.Error("expected unknown nested key error")
+	}
+}
+
+func TestJSONLinesRoundTrip(t *testing.T) {
+	src := NewJournal("./test_journals/default")
+
+	var buf bytes.Buffer
+	n, err := src.ExportJSONLines(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || strings.Count(buf.String(), "\n") != 2 {
+		t.Fatalf("expected 2 lines, got:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"text":"#title line\n\nbody line"`) {
+		t.Error("expected text without html escaping")
+	}
+
+	dst, cleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanup()
+
+	n, err = dst.ImportJSONLines(strings.NewReader(buf.String() + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 imported, got %d", n)
+	}
+
+	for _, uuid := range []string{"871D0F435D7B469C9429CD441A9E74B5", "FF755C6D7D9B4A5FBC4E41C07D622C65"} {
+		a, err := src.ReadEntry(uuid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := dst.ReadEntry(uuid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changes := DiffEntries(a, b); len(changes) != 0 {
+			t.Errorf("unexpected changes: %v", changes)
+		}
+	}
+}
+
+func TestImportJSONLinesBadLine(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanup()
+
+	input := `{"text":"first","creation_date":"2015-01-02T03:04:05Z","tags":[],"starred":false}` + "\n" +
+		"not json\n"
+	n, err := j.ImportJSONLines(strings.NewReader(input))
+	if n != 1 {
+		t.Errorf("expected 1 imported, got %d", n)
+	}
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected error on line 2, got %v", err)
+	}
+}
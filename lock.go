@@ -0,0 +1,45 @@
+package dayone
+
+import (
+	"github.com/juju/errgo"
+	"os"
+	"path/filepath"
+)
+
+// lockFileName is the name of the file in the journal
+// dir used for advisory locking between processes.
+const lockFileName = ".lock"
+
+// lock takes an exclusive advisory lock on the journal,
+// blocking until it is available. The returned func
+// releases the lock. Every mutating method holds the lock
+// for its whole duration, reads included, so concurrent
+// read-modify-write operations can't lose updates.
+//
+// The lock isn't re-entrant: each call opens its own file,
+// and a second lock blocks even in the same process. Code
+// holding the lock must never call lock again, directly or
+// through a public mutating method like Write, WritePhoto or
+// Delete. It uses the internal helpers that assume the lock
+// is held instead: writeEntry, save, write, writePhoto,
+// delete and restore.
+func (j *Journal) lock() (func(), error) {
+	if err := os.MkdirAll(j.dir, 0755); err != nil {
+		return nil, errgo.Mask(err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(j.dir, lockFileName), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, errgo.Mask(err)
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package dayone
+
+import (
+	"os"
+)
+
+// Advisory locking isn't supported on this platform so
+// writers aren't coordinated across processes.
+
+func lockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
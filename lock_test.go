@@ -0,0 +1,81 @@
+package dayone
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockIsExclusive(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanup()
+
+	unlock, err := j.lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locked := make(chan struct{})
+	go func() {
+		unlock2, err := NewJournal(j.dir).lock()
+		if err != nil {
+			t.Error(err)
+		} else {
+			unlock2()
+		}
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("second lock acquired while first was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("second lock wasn't acquired after unlock")
+	}
+}
+
+func TestUpdateHoldsLockThroughout(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	locked := make(chan struct{})
+	started := false
+	_, err := j.Update(nil, func(e *Entry) error {
+		if !started {
+			started = true
+			go func() {
+				unlock, err := NewJournal(j.dir).lock()
+				if err != nil {
+					t.Error(err)
+				} else {
+					unlock()
+				}
+				close(locked)
+			}()
+		}
+
+		select {
+		case <-locked:
+			t.Error("lock acquired while an update was running")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		e.Starred = false
+		return nil
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("lock wasn't released after the update")
+	}
+}
@@ -0,0 +1,209 @@
+package dayone
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// renderMarkdown converts entry text to HTML. It handles the
+// Markdown Day One supports: headings, paragraphs (where single
+// newlines are line breaks), block quotes, lists, fenced code,
+// horizontal rules, emphasis, strikethrough, code spans, links,
+// images and bare URLs. Raw HTML in the text is escaped, and
+// empty elements are self-closed so the output is XHTML too.
+//
+// As in ExtractHashtags, any line starting with '#' is a
+// heading, even without a space after the '#'s.
+func renderMarkdown(text string) string {
+	var r mdRenderer
+	lines := strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			r.closeBlock()
+
+		case isCodeFence(trimmed):
+			r.closeBlock()
+			fence := trimmed[:3]
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			fmt.Fprintf(&r.out, "<pre><code>%s</code></pre>\n", html.EscapeString(strings.Join(code, "\n")))
+
+		case mdRulePattern.MatchString(trimmed):
+			r.closeBlock()
+			r.out.WriteString("<hr />\n")
+
+		case headingPattern.MatchString(line):
+			r.closeBlock()
+			m := mdHeadingPattern.FindStringSubmatch(trimmed)
+			level := len(m[1])
+			if level > 6 {
+				level = 6
+			}
+			fmt.Fprintf(&r.out, "<h%d>%s</h%d>\n", level, renderInline(m[2]), level)
+
+		case strings.HasPrefix(trimmed, ">"):
+			r.open("blockquote")
+			r.addLine(strings.TrimSpace(strings.TrimPrefix(trimmed, ">")))
+
+		case mdBulletPattern.MatchString(line):
+			r.item("ul", mdBulletPattern.ReplaceAllString(line, ""))
+
+		case mdNumberPattern.MatchString(line):
+			r.item("ol", mdNumberPattern.ReplaceAllString(line, ""))
+
+		default:
+			if r.block != "p" && r.block != "blockquote" {
+				r.open("p")
+			}
+			r.addLine(trimmed)
+		}
+	}
+
+	r.closeBlock()
+	return r.out.String()
+}
+
+var (
+	mdHeadingPattern = regexp.MustCompile(`^(#+)\s*(.*?)\s*#*$`)
+	mdRulePattern    = regexp.MustCompile(`^(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	mdBulletPattern  = regexp.MustCompile(`^ {0,3}[-*+]\s+`)
+	mdNumberPattern  = regexp.MustCompile(`^ {0,3}\d+[.)]\s+`)
+)
+
+type mdRenderer struct {
+	out   strings.Builder
+	block string   // the open block element, if any
+	lines []string // lines of the open paragraph or quote
+}
+
+func (r *mdRenderer) open(block string) {
+	if r.block != block {
+		r.closeBlock()
+		r.block = block
+		fmt.Fprintf(&r.out, "<%s>", block)
+		if block != "p" {
+			r.out.WriteString("\n")
+		}
+	}
+}
+
+func (r *mdRenderer) addLine(line string) {
+	r.lines = append(r.lines, renderInline(line))
+}
+
+func (r *mdRenderer) item(list, text string) {
+	r.open(list)
+	fmt.Fprintf(&r.out, "<li>%s</li>\n", renderInline(strings.TrimSpace(text)))
+}
+
+func (r *mdRenderer) closeBlock() {
+	switch r.block {
+	case "":
+		return
+	case "blockquote":
+		fmt.Fprintf(&r.out, "<p>%s</p>\n", strings.Join(r.lines, "<br />\n"))
+	case "p":
+		r.out.WriteString(strings.Join(r.lines, "<br />\n"))
+	}
+	fmt.Fprintf(&r.out, "</%s>\n", r.block)
+	r.block, r.lines = "", nil
+}
+
+var (
+	mdImagePattern  = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`)
+	mdLinkPattern   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdStrongPattern = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*|__(\S(?:.*?\S)?)__`)
+	mdEmPattern     = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*|(?:^|\b)_(\S(?:[^_]*?\S)?)_(?:\b|$)`)
+	mdStrikePattern = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	mdTokenPattern  = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// renderInline converts the inline Markdown in a line of text.
+// Code spans, links and images are swapped for numbered tokens
+// first so the other rules can't touch their contents.
+func renderInline(text string) string {
+	text = strings.Replace(text, "\x00", "", -1)
+
+	var tokens []string
+	token := func(s string) string {
+		tokens = append(tokens, s)
+		return fmt.Sprintf("\x00%d\x00", len(tokens)-1)
+	}
+
+	text = codeSpanPattern.ReplaceAllStringFunc(text, func(s string) string {
+		ticks := len(s) - len(strings.TrimLeft(s, "`"))
+		code := strings.TrimRight(s[ticks:], "`")
+		return token("<code>" + html.EscapeString(strings.TrimSpace(code)) + "</code>")
+	})
+
+	text = mdImagePattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := mdImagePattern.FindStringSubmatch(s)
+		if !safeURL(m[2]) {
+			return token(html.EscapeString(s))
+		}
+		return token(fmt.Sprintf(`<img src="%s" alt="%s" />`, html.EscapeString(m[2]), html.EscapeString(m[1])))
+	})
+
+	text = mdLinkPattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := mdLinkPattern.FindStringSubmatch(s)
+		if !safeURL(m[2]) {
+			return token(html.EscapeString(s))
+		}
+		return token(fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(m[2]), renderEmphasis(html.EscapeString(m[1]))))
+	})
+
+	text = urlPattern.ReplaceAllStringFunc(text, func(s string) string {
+		// Leave trailing punctuation out of the link.
+		trimmed := strings.TrimRight(s, ".,;:!?)'\"")
+		rest := s[len(trimmed):]
+		s = trimmed
+
+		href := s
+		if strings.HasPrefix(strings.ToLower(s), "www.") {
+			href = "http://" + s
+		}
+		return token(fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(href), html.EscapeString(s))) + rest
+	})
+
+	text = renderEmphasis(html.EscapeString(text))
+
+	// Link text can hold code span tokens, so restore until
+	// none are left.
+	for mdTokenPattern.MatchString(text) {
+		text = mdTokenPattern.ReplaceAllStringFunc(text, func(s string) string {
+			i, _ := strconv.Atoi(s[1 : len(s)-1])
+			return tokens[i]
+		})
+	}
+	return text
+}
+
+// renderEmphasis converts bold, italic and strikethrough
+// markers in already escaped text.
+func renderEmphasis(text string) string {
+	text = mdStrongPattern.ReplaceAllString(text, "<strong>$1$2</strong>")
+	text = mdStrikePattern.ReplaceAllString(text, "<del>$1</del>")
+	return mdEmPattern.ReplaceAllString(text, "<em>$1$2</em>")
+}
+
+// safeURL reports whether url is safe to link to: relative,
+// or using the http, https or mailto scheme.
+func safeURL(url string) bool {
+	lower := strings.ToLower(url)
+	if i := strings.IndexAny(lower, ":/?#"); i >= 0 && lower[i] == ':' {
+		return strings.HasPrefix(lower, "http:") ||
+			strings.HasPrefix(lower, "https:") ||
+			strings.HasPrefix(lower, "mailto:")
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package dayone
+
+import (
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"#title line\n\nbody line", "<h1>title line</h1>\n<p>body line</p>\n"},
+		{"## Two ##", "<h2>Two</h2>\n"},
+		{"one\ntwo\n\nthree", "<p>one<br />\ntwo</p>\n<p>three</p>\n"},
+		{"> quoted\n> more", "<blockquote>\n<p>quoted<br />\nmore</p>\n</blockquote>\n"},
+		{"- a\n* b\n\n1. c\n2) d", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n<ol>\n<li>c</li>\n<li>d</li>\n</ol>\n"},
+		{"```\n<b>x</b>\n  y\n```", "<pre><code>&lt;b&gt;x&lt;/b&gt;\n  y</code></pre>\n"},
+		{"---", "<hr />\n"},
+		{"**bold** *em* _em_ ~~gone~~", "<p><strong>bold</strong> <em>em</em> <em>em</em> <del>gone</del></p>\n"},
+		{"snake_case_name", "<p>snake_case_name</p>\n"},
+		{"`**not bold**`", "<p><code>**not bold**</code></p>\n"},
+		{"<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"[site](http://example.com/a_b_c)", `<p><a href="http://example.com/a_b_c">site</a></p>` + "\n"},
+		{"[`code`](http://example.com)", `<p><a href="http://example.com"><code>code</code></a></p>` + "\n"},
+		{"[x](javascript:alert(1))", "<p>[x](javascript:alert(1))</p>\n"},
+		{"![pic](photo.jpg)", `<p><img src="photo.jpg" alt="pic" /></p>` + "\n"},
+		{"see www.example.com.", `<p>see <a href="http://www.example.com">www.example.com</a>.</p>` + "\n"},
+		{"null \x000\x00 byte", "<p>null 0 byte</p>\n"},
+	}
+
+	for _, test := range tests {
+		if actual := renderMarkdown(test.text); actual != test.expected {
+			t.Errorf("renderMarkdown(%q)\n got: %q\nwant: %q", test.text, actual, test.expected)
+		}
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	for url, expected := range map[string]bool{
+		"http://example.com":     true,
+		"HTTPS://example.com":    true,
+		"mailto:me@example.com":  true,
+		"photos/a.jpg":           true,
+		"page?x=a:b":             true,
+		"javascript:alert(1)":    false,
+		"data:text/html;base64,": false,
+	} {
+		if safeURL(url) != expected {
+			t.Errorf("safeURL(%q) should be %v", url, expected)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package dayone
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// MergeOptions configures Journal.Merge.
+type MergeOptions struct {
+	// Rekey imports source entries whose uuid is already
+	// used by a different entry in the destination under a
+	// fresh uuid. Otherwise they are skipped and reported
+	// as conflicts.
+	Rekey bool
+}
+
+// MergeReport summarizes the result of Journal.Merge.
+// Source uuids are taken from the entry file names in
+// the source journal.
+type MergeReport struct {
+	// Imported are the uuids of entries copied as they were.
+	Imported []string
+
+	// Identical are the uuids of entries already in the
+	// destination with the same content.
+	Identical []string
+
+	// Rekeyed maps the source uuid of entries imported
+	// under a different uuid to that uuid: colliding
+	// entries, and entries whose file name and plist
+	// UUID disagree.
+	Rekeyed map[string]string
+
+	// Conflicts are the uuids of colliding entries that
+	// weren't imported because Rekey wasn't set.
+	Conflicts []string
+
+	// Photos are the uuids (in the destination) of the
+	// photos that were copied.
+	Photos []string
+
+	// Failed are the source entries that couldn't be read
+	// or written, keyed by source uuid.
+	Failed map[string]error
+}
+
+// Merge imports all the entries and photos from src into the
+// journal. An entry whose uuid is already in the journal is
+// skipped when its content (including its photo) is the same,
+// and otherwise is a collision handled according to opts,
+// which may be nil.
+func (j *Journal) Merge(src *Journal, opts *MergeOptions) (*MergeReport, error) {
+	var o MergeOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	unlock, err := j.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	uuids, err := src.entryUUIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MergeReport{
+		Rekeyed: make(map[string]string),
+		Failed:  make(map[string]error),
+	}
+
+	for _, uuid := range uuids {
+		// The source files are named after uuid, which can
+		// differ from the entry's plist UUID.
+		e, err := src.ReadEntry(uuid)
+		if err != nil {
+			report.Failed[uuid] = err
+			continue
+		}
+
+		same, err := j.sameEntry(src, uuid, e)
+		if err != nil {
+			report.Failed[uuid] = err
+			continue
+		}
+
+		switch {
+		case same:
+			// Fill in a photo the destination is missing.
+			copied, err := j.copyPhoto(src, uuid, e.uuid, false)
+			if err != nil {
+				report.Failed[uuid] = err
+				continue
+			}
+			if copied {
+				report.Photos = append(report.Photos, e.uuid)
+			}
+			report.Identical = append(report.Identical, uuid)
+			continue
+		case j.hasEntry(e.uuid) && !o.Rekey:
+			report.Conflicts = append(report.Conflicts, uuid)
+			continue
+		case j.hasEntry(e.uuid):
+			e.uuid = newEntry().uuid
+		}
+
+		e.forgetStat()
+		if err := j.writeEntry(e); err != nil {
+			report.Failed[uuid] = err
+			continue
+		}
+
+		copied, err := j.copyPhoto(src, uuid, e.uuid, true)
+		if err != nil {
+			report.Failed[uuid] = err
+			continue
+		}
+		if copied {
+			report.Photos = append(report.Photos, e.uuid)
+		}
+
+		if e.uuid != uuid {
+			report.Rekeyed[uuid] = e.uuid
+		} else {
+			report.Imported = append(report.Imported, uuid)
+		}
+	}
+
+	return report, nil
+}
+
+func (j *Journal) hasEntry(uuid string) bool {
+	_, err := j.EntryStat(uuid)
+	return err == nil
+}
+
+// sameEntry reports whether the journal already has an entry
+// and photo with the same content as e, read from the file
+// for srcUUID in src.
+func (j *Journal) sameEntry(src *Journal, srcUUID string, e *Entry) (bool, error) {
+	existing, err := j.ReadEntry(e.UUID())
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if len(DiffEntries(existing, e)) > 0 {
+		return false, nil
+	}
+
+	srcHash, err := hashFile(filepath.Join(src.getPhotosDir(), srcUUID+photoExt))
+	if os.IsNotExist(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	dstHash, err := hashFile(filepath.Join(j.getPhotosDir(), e.UUID()+photoExt))
+	if os.IsNotExist(err) {
+		// Only the source has a photo, which can be copied.
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return srcHash == dstHash, nil
+}
+
+// copyPhoto copies the photo for srcUUID in src to dstUUID in
+// the journal, if there is one. An existing photo is only
+// replaced when overwrite is true. It reports whether the
+// photo was copied. The caller must hold the lock.
+func (j *Journal) copyPhoto(src *Journal, srcUUID, dstUUID string, overwrite bool) (bool, error) {
+	if !overwrite {
+		if _, err := j.PhotoStat(dstUUID); err == nil {
+			return false, nil
+		}
+	}
+
+	r, err := src.OpenPhoto(srcUUID)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	if err := j.writePhoto(dstUUID, r); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,172 @@
+package dayone
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMergeIntoEmptyJournal(t *testing.T) {
+	dst, cleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanup()
+
+	report, err := dst.Merge(NewJournal("./test_journals/default"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"871D0F435D7B469C9429CD441A9E74B5", "FF755C6D7D9B4A5FBC4E41C07D622C65"}
+	if !reflect.DeepEqual(report.Imported, expected) {
+		t.Errorf("unexpected imported: %v", report.Imported)
+	}
+
+	if !reflect.DeepEqual(report.Photos, expected[:1]) {
+		t.Errorf("unexpected photos: %v", report.Photos)
+	}
+
+	if len(report.Failed) != 0 {
+		t.Errorf("unexpected failures: %v", report.Failed)
+	}
+
+	if _, err := dst.PhotoStat("871D0F435D7B469C9429CD441A9E74B5"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMergeIdenticalJournal(t *testing.T) {
+	dst, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	report, err := dst.Merge(NewJournal("./test_journals/default"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Identical) != 2 || len(report.Imported) != 0 || len(report.Photos) != 0 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestMergeCollisions(t *testing.T) {
+	src, cleanupSrc := copyJournal(t, "./test_journals/default")
+	defer cleanupSrc()
+
+	e, err := src.ReadEntry("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.EntryText = "a different entry"
+	if err := src.Write(e); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, cleanupDst := copyJournal(t, "./test_journals/default")
+	defer cleanupDst()
+
+	report, err := dst.Merge(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(report.Conflicts, []string{"FF755C6D7D9B4A5FBC4E41C07D622C65"}) {
+		t.Errorf("unexpected conflicts: %v", report.Conflicts)
+	}
+
+	report, err = dst.Merge(src, &MergeOptions{Rekey: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newUUID := report.Rekeyed["FF755C6D7D9B4A5FBC4E41C07D622C65"]
+	if newUUID == "" || len(report.Conflicts) != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	rekeyed, err := dst.ReadEntry(newUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rekeyed.EntryText != "a different entry" {
+		t.Error("rekeyed entry text")
+	}
+
+	original, err := dst.ReadEntry("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if original.EntryText == "a different entry" {
+		t.Error("original entry was overwritten")
+	}
+}
+
+func TestMergeReportsFailedPhotoCopy(t *testing.T) {
+	dst, cleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanup()
+
+	src, srcCleanup := copyJournal(t, "./test_journals/default")
+	defer srcCleanup()
+
+	// A dir in place of the photo can be opened but not read.
+	photo := filepath.Join(src.getPhotosDir(), photoUUID+photoExt)
+	if err := os.Remove(photo); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(photo, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := dst.Merge(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := report.Failed[photoUUID]; !ok {
+		t.Errorf("expected %s to fail, got %v", photoUUID, report.Failed)
+	}
+	if !reflect.DeepEqual(report.Imported, []string{syncUUID}) {
+		t.Errorf("unexpected imported: %v", report.Imported)
+	}
+}
+
+func TestMergeMismatchedFile(t *testing.T) {
+	dst, cleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanup()
+
+	src, srcCleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer srcCleanup()
+
+	// The file and photo are named after copiedEntryUUID
+	// but the plist UUID is syncUUID.
+	def := NewJournal("./test_journals/default")
+	b, err := ioutil.ReadFile(filepath.Join(def.getEntriesDir(), syncUUID+entryExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src.getEntriesDir(), copiedEntryUUID+entryExt), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(def.getPhotosDir(), photoUUID+photoExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src.getPhotosDir(), copiedEntryUUID+photoExt), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := dst.Merge(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(report.Rekeyed, map[string]string{copiedEntryUUID: syncUUID}) {
+		t.Errorf("unexpected rekeyed: %v", report.Rekeyed)
+	}
+	if !reflect.DeepEqual(report.Photos, []string{syncUUID}) {
+		t.Errorf("unexpected photos: %v", report.Photos)
+	}
+	if _, err := dst.PhotoStat(syncUUID); err != nil {
+		t.Error(err)
+	}
+}
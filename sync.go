@@ -0,0 +1,446 @@
+package dayone
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/juju/errgo"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// SyncResolution is the side a SyncPolicy picks to win.
+type SyncResolution int
+
+const (
+	KeepA SyncResolution = iota
+	KeepB
+)
+
+// SyncConflict is an entry that changed in both journals since
+// they were last synced, or changed in one and was deleted in
+// the other. A or B is nil when the entry was deleted there.
+type SyncConflict struct {
+	UUID     string
+	A        *Entry
+	B        *Entry
+	AModTime time.Time
+	BModTime time.Time
+}
+
+// SyncPolicy resolves a conflict found by Sync.
+type SyncPolicy func(c *SyncConflict) (SyncResolution, error)
+
+// PreferA is a SyncPolicy where journal a always wins.
+func PreferA(c *SyncConflict) (SyncResolution, error) {
+	return KeepA, nil
+}
+
+// PreferB is a SyncPolicy where journal b always wins.
+func PreferB(c *SyncConflict) (SyncResolution, error) {
+	return KeepB, nil
+}
+
+// PreferNewer is a SyncPolicy where the most recently modified
+// entry wins. An edit always wins over a deletion.
+func PreferNewer(c *SyncConflict) (SyncResolution, error) {
+	switch {
+	case c.A == nil:
+		return KeepB, nil
+	case c.B == nil:
+		return KeepA, nil
+	case c.BModTime.After(c.AModTime):
+		return KeepB, nil
+	}
+	return KeepA, nil
+}
+
+// SyncOptions configures Sync.
+type SyncOptions struct {
+	// StateFile is where the sync state is kept between runs.
+	// Defaults to a file in a ".sync" dir inside journal a
+	// named after journal b, so the same a and b must be
+	// passed in the same order each time.
+	StateFile string
+
+	// Policy resolves conflicting changes.
+	// Defaults to PreferNewer.
+	Policy SyncPolicy
+}
+
+// SyncReport summarizes the result of Sync.
+type SyncReport struct {
+	CopiedToA    []string
+	CopiedToB    []string
+	DeletedFromA []string
+	DeletedFromB []string
+
+	// Conflicts are the uuids resolved using the policy.
+	Conflicts []string
+
+	// Failed are the uuids that couldn't be synced.
+	Failed map[string]error
+}
+
+// ErrSyncSameJournal is returned by Sync when a and b are the
+// same journal, including through a symlink.
+var ErrSyncSameJournal = errors.New("can't sync a journal with itself")
+
+// syncState is what Sync remembers between runs.
+type syncState struct {
+	// Synced maps the uuid of each entry in both journals
+	// after the last sync to the hash of its content.
+	Synced map[string]string `json:"synced"`
+
+	// Tombstones maps the uuid of each entry deleted by a
+	// sync to the hash of its content when it was deleted.
+	Tombstones map[string]tombstone `json:"tombstones"`
+}
+
+type tombstone struct {
+	Hash    string    `json:"hash"`
+	Deleted time.Time `json:"deleted"`
+}
+
+// Sync reconciles journals a and b in both directions. An entry
+// (along with its photo) added, changed or deleted in one journal
+// since the last sync is added, changed or deleted in the other.
+// Entries changed in both are resolved by the policy.
+//
+// Deleted entries are remembered with tombstones, so a stale
+// copy of a deleted entry that reappears in either journal is
+// deleted again rather than resurrected. opts may be nil.
+// It returns ErrSyncSameJournal if a and b are the same dir.
+func Sync(a, b *Journal, opts *SyncOptions) (*SyncReport, error) {
+	var o SyncOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Policy == nil {
+		o.Policy = PreferNewer
+	}
+	if o.StateFile == "" {
+		abs, err := filepath.Abs(b.dir)
+		if err != nil {
+			return nil, errgo.Mask(err)
+		}
+		sum := sha1.Sum([]byte(abs))
+		o.StateFile = filepath.Join(a.dir, ".sync", hex.EncodeToString(sum[:8])+".json")
+	}
+
+	// Locking the same journal twice would deadlock, and
+	// locking in a consistent order means concurrent syncs
+	// of the same pair can't deadlock either.
+	aDir, err := realPath(a.dir)
+	if err != nil {
+		return nil, err
+	}
+	bDir, err := realPath(b.dir)
+	if err != nil {
+		return nil, err
+	}
+	if aDir == bDir {
+		return nil, ErrSyncSameJournal
+	}
+
+	first, second := a, b
+	if bDir < aDir {
+		first, second = b, a
+	}
+	for _, j := range []*Journal{first, second} {
+		unlock, err := j.lock()
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	state, err := loadSyncState(o.StateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &syncer{
+		a:      a,
+		b:      b,
+		policy: o.Policy,
+		state:  state,
+		report: &SyncReport{Failed: make(map[string]error)},
+	}
+
+	uuids, err := s.uuids()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, uuid := range uuids {
+		if err := s.sync(uuid); err != nil {
+			s.report.Failed[uuid] = err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(o.StateFile), 0755); err != nil {
+		return nil, errgo.Mask(err)
+	}
+	err = writeFileAtomic(o.StateFile, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(s.state)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.report, nil
+}
+
+// realPath returns the absolute path of dir with any
+// symlinks resolved.
+func realPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", errgo.Mask(err)
+	}
+
+	path, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", err
+		} else {
+			return "", errgo.Mask(err)
+		}
+	}
+	return path, nil
+}
+
+func loadSyncState(path string) (*syncState, error) {
+	state := &syncState{
+		Synced:     make(map[string]string),
+		Tombstones: make(map[string]tombstone),
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(state); err != nil {
+		return nil, errgo.Notef(err, "invalid sync state %s", path)
+	}
+	return state, nil
+}
+
+type syncer struct {
+	a, b   *Journal
+	policy SyncPolicy
+	state  *syncState
+	report *SyncReport
+}
+
+// uuids returns every uuid in either journal or the state.
+func (s *syncer) uuids() ([]string, error) {
+	set := make(map[string]bool)
+	for _, j := range []*Journal{s.a, s.b} {
+		uuids, err := j.entryUUIDs()
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, uuid := range uuids {
+			set[uuid] = true
+		}
+	}
+	for uuid := range s.state.Synced {
+		set[uuid] = true
+	}
+
+	var out []string
+	for uuid := range set {
+		out = append(out, uuid)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func (s *syncer) sync(uuid string) error {
+	ha, err := s.a.contentHash(uuid)
+	if err != nil {
+		return err
+	}
+	hb, err := s.b.contentHash(uuid)
+	if err != nil {
+		return err
+	}
+	base := s.state.Synced[uuid]
+
+	// A stale copy of a deleted entry came back.
+	if t, ok := s.state.Tombstones[uuid]; ok && base == "" {
+		switch {
+		case ha == t.Hash && hb == t.Hash:
+			// Both journals got it back, e.g. from a backup.
+			if err := s.remove(s.a, uuid, ha, &s.report.DeletedFromA); err != nil {
+				return err
+			}
+			return s.remove(s.b, uuid, hb, &s.report.DeletedFromB)
+		case ha == "" && hb == t.Hash:
+			return s.remove(s.b, uuid, hb, &s.report.DeletedFromB)
+		case hb == "" && ha == t.Hash:
+			return s.remove(s.a, uuid, ha, &s.report.DeletedFromA)
+		}
+	}
+
+	switch {
+	case ha == hb:
+		s.settle(uuid, ha, base)
+		return nil
+	case ha == base:
+		return s.apply(s.b, s.a, uuid, hb, &s.report.CopiedToA, &s.report.DeletedFromA)
+	case hb == base:
+		return s.apply(s.a, s.b, uuid, ha, &s.report.CopiedToB, &s.report.DeletedFromB)
+	}
+
+	c, err := s.conflict(uuid)
+	if err != nil {
+		return err
+	}
+	res, err := s.policy(c)
+	if err != nil {
+		return err
+	}
+
+	s.report.Conflicts = append(s.report.Conflicts, uuid)
+	if res == KeepB {
+		return s.apply(s.b, s.a, uuid, hb, &s.report.CopiedToA, &s.report.DeletedFromA)
+	}
+	return s.apply(s.a, s.b, uuid, ha, &s.report.CopiedToB, &s.report.DeletedFromB)
+}
+
+// apply makes the entry in dst match src, whose content hash is
+// hash, recording the uuid in copied or deleted.
+func (s *syncer) apply(src, dst *Journal, uuid, hash string, copied, deleted *[]string) error {
+	if hash == "" {
+		return s.remove(dst, uuid, s.state.Synced[uuid], deleted)
+	}
+
+	err := dst.versioned(uuid, func() error {
+		return copyEntryFiles(src, dst, uuid)
+	})
+	if err != nil {
+		return err
+	}
+	*copied = append(*copied, uuid)
+	s.settle(uuid, hash, s.state.Synced[uuid])
+	return nil
+}
+
+// remove deletes the entry from j and leaves a tombstone.
+func (s *syncer) remove(j *Journal, uuid, hash string, deleted *[]string) error {
+	if err := j.delete(uuid); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	*deleted = append(*deleted, uuid)
+	s.settle(uuid, "", hash)
+	return nil
+}
+
+// settle records that both journals now have content hash for
+// uuid, where "" means the entry is gone from both.
+func (s *syncer) settle(uuid, hash, old string) {
+	if hash != "" {
+		s.state.Synced[uuid] = hash
+		delete(s.state.Tombstones, uuid)
+		return
+	}
+
+	delete(s.state.Synced, uuid)
+	if old != "" {
+		s.state.Tombstones[uuid] = tombstone{Hash: old, Deleted: time.Now().UTC()}
+	}
+}
+
+func (s *syncer) conflict(uuid string) (*SyncConflict, error) {
+	c := &SyncConflict{UUID: uuid}
+
+	var err error
+	if c.A, c.AModTime, err = readIfExists(s.a, uuid); err != nil {
+		return nil, err
+	}
+	if c.B, c.BModTime, err = readIfExists(s.b, uuid); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func readIfExists(j *Journal, uuid string) (*Entry, time.Time, error) {
+	e, err := j.ReadEntry(uuid)
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, nil
+	} else if err != nil {
+		return nil, time.Time{}, err
+	}
+	return e, e.modTime, nil
+}
+
+// contentHash hashes the entry file and photo for uuid together.
+// It returns "" if the journal has no entry with that uuid.
+func (j *Journal) contentHash(uuid string) (string, error) {
+	entry, err := hashFile(filepath.Join(j.getEntriesDir(), uuid+entryExt))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	photo, err := hashFile(filepath.Join(j.getPhotosDir(), uuid+photoExt))
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	return entry + ":" + photo, nil
+}
+
+// copyEntryFiles copies the entry and photo files for uuid
+// byte for byte, removing the photo in dst if src has none.
+// The caller must hold the lock on dst.
+func copyEntryFiles(src, dst *Journal, uuid string) error {
+	if err := copyFile(
+		filepath.Join(src.getEntriesDir(), uuid+entryExt),
+		filepath.Join(dst.getEntriesDir(), uuid+entryExt),
+	); err != nil {
+		return err
+	}
+
+	err := copyFile(
+		filepath.Join(src.getPhotosDir(), uuid+photoExt),
+		filepath.Join(dst.getPhotosDir(), uuid+photoExt),
+	)
+	if os.IsNotExist(err) {
+		return removeFiles([]string{filepath.Join(dst.getPhotosDir(), uuid+photoExt)})
+	}
+	return err
+}
+
+func copyFile(src, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return err
+		} else {
+			return errgo.Mask(err)
+		}
+	}
+	defer f.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return errgo.Mask(err)
+	}
+
+	return writeFileAtomic(dst, func(w io.Writer) error {
+		_, err := io.Copy(w, f)
+		return err
+	})
+}
@@ -0,0 +1,323 @@
+package dayone
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	syncUUID  = "FF755C6D7D9B4A5FBC4E41C07D622C65"
+	photoUUID = "871D0F435D7B469C9429CD441A9E74B5"
+)
+
+func TestSyncIntoEmptyJournal(t *testing.T) {
+	a, cleanupA := copyJournal(t, "./test_journals/default")
+	defer cleanupA()
+	b, cleanupB := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanupB()
+
+	report, err := Sync(a, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{photoUUID, syncUUID}
+	if !reflect.DeepEqual(report.CopiedToB, expected) {
+		t.Errorf("unexpected copied: %v", report.CopiedToB)
+	}
+
+	if _, err := b.PhotoStat(photoUUID); err != nil {
+		t.Error(err)
+	}
+
+	report, err = Sync(a, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.CopiedToA)+len(report.CopiedToB)+len(report.Conflicts) != 0 {
+		t.Errorf("expected second sync to do nothing: %+v", report)
+	}
+}
+
+func syncedJournals(t *testing.T) (*Journal, *Journal, func()) {
+	a, cleanupA := copyJournal(t, "./test_journals/default")
+	b, cleanupB := copyJournal(t, "./test_journals/empty_with_dirs")
+	cleanup := func() {
+		cleanupA()
+		cleanupB()
+	}
+
+	if _, err := Sync(a, b, nil); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return a, b, cleanup
+}
+
+func TestSyncEdit(t *testing.T) {
+	a, b, cleanup := syncedJournals(t)
+	defer cleanup()
+
+	e, err := b.ReadEntry(syncUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.EntryText = "edited on b"
+	if err := b.Write(e); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Sync(a, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.CopiedToA, []string{syncUUID}) {
+		t.Errorf("unexpected copied: %v", report.CopiedToA)
+	}
+
+	e, err = a.ReadEntry(syncUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.EntryText != "edited on b" {
+		t.Error("expected edit to be synced")
+	}
+}
+
+func TestSyncDeleteLeavesTombstone(t *testing.T) {
+	a, b, cleanup := syncedJournals(t)
+	defer cleanup()
+
+	// Keep a stale copy to bring back later.
+	stale, err := ioutil.ReadFile(filepath.Join(a.getEntriesDir(), syncUUID+entryExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Delete(syncUUID); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Sync(a, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.DeletedFromB, []string{syncUUID}) {
+		t.Errorf("unexpected deleted: %v", report.DeletedFromB)
+	}
+	if _, err := b.EntryStat(syncUUID); !os.IsNotExist(err) {
+		t.Error("expected entry to be deleted from b")
+	}
+
+	err = ioutil.WriteFile(filepath.Join(b.getEntriesDir(), syncUUID+entryExt), stale, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = Sync(a, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.CopiedToA) != 0 || !reflect.DeepEqual(report.DeletedFromB, []string{syncUUID}) {
+		t.Errorf("expected stale copy to be deleted: %+v", report)
+	}
+	if _, err := a.EntryStat(syncUUID); !os.IsNotExist(err) {
+		t.Error("expected entry not to be resurrected")
+	}
+}
+
+func TestSyncDeletesStaleCopiesInBoth(t *testing.T) {
+	a, b, cleanup := syncedJournals(t)
+	defer cleanup()
+
+	stale, err := ioutil.ReadFile(filepath.Join(a.getEntriesDir(), syncUUID+entryExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Delete(syncUUID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Sync(a, b, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, j := range []*Journal{a, b} {
+		err := ioutil.WriteFile(filepath.Join(j.getEntriesDir(), syncUUID+entryExt), stale, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := Sync(a, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.DeletedFromA, []string{syncUUID}) || !reflect.DeepEqual(report.DeletedFromB, []string{syncUUID}) {
+		t.Errorf("expected stale copies to be deleted: %+v", report)
+	}
+	for _, j := range []*Journal{a, b} {
+		if _, err := j.EntryStat(syncUUID); !os.IsNotExist(err) {
+			t.Errorf("expected entry not to be resurrected in %s", j.dir)
+		}
+	}
+}
+
+func TestSyncSameJournal(t *testing.T) {
+	a, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	link := a.dir + "-link"
+	if err := os.Symlink(a.dir, link); err != nil {
+		t.Skip(err)
+	}
+	defer os.Remove(link)
+
+	for _, b := range []*Journal{a, NewJournal(link)} {
+		if _, err := Sync(a, b, nil); err != ErrSyncSameJournal {
+			t.Errorf("%s: expected ErrSyncSameJournal, got %v", b.dir, err)
+		}
+	}
+}
+
+func TestSyncDeletesPhoto(t *testing.T) {
+	a, b, cleanup := syncedJournals(t)
+	defer cleanup()
+
+	if err := b.Delete(photoUUID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Sync(a, b, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.PhotoStat(photoUUID); !os.IsNotExist(err) {
+		t.Error("expected photo to be deleted from a")
+	}
+}
+
+func editBoth(t *testing.T, a, b *Journal) {
+	for _, j := range []*Journal{a, b} {
+		e, err := j.ReadEntry(syncUUID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.EntryText = "edited in " + j.dir
+		if err := j.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSyncConflictPolicies(t *testing.T) {
+	for _, test := range []struct {
+		policy SyncPolicy
+		winner func(a, b *Journal) *Journal
+	}{
+		{PreferA, func(a, b *Journal) *Journal { return a }},
+		{PreferB, func(a, b *Journal) *Journal { return b }},
+	} {
+		a, b, cleanup := syncedJournals(t)
+		editBoth(t, a, b)
+
+		report, err := Sync(a, b, &SyncOptions{Policy: test.policy})
+		if err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(report.Conflicts, []string{syncUUID}) {
+			t.Errorf("unexpected conflicts: %v", report.Conflicts)
+		}
+
+		expected := "edited in " + test.winner(a, b).dir
+		for _, j := range []*Journal{a, b} {
+			e, err := j.ReadEntry(syncUUID)
+			if err != nil {
+				t.Error(err)
+			} else if e.EntryText != expected {
+				t.Errorf("expected %q, got %q", expected, e.EntryText)
+			}
+		}
+		cleanup()
+	}
+}
+
+func TestSyncPreferNewer(t *testing.T) {
+	a, b, cleanup := syncedJournals(t)
+	defer cleanup()
+
+	editBoth(t, a, b)
+
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(b.getEntriesDir(), syncUUID+entryExt), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Sync(a, b, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := b.ReadEntry(syncUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.EntryText != "edited in "+a.dir {
+		t.Error("expected newer edit from a to win")
+	}
+}
+
+func TestSyncEditWinsOverDelete(t *testing.T) {
+	a, b, cleanup := syncedJournals(t)
+	defer cleanup()
+
+	if err := a.Delete(syncUUID); err != nil {
+		t.Fatal(err)
+	}
+	e, err := b.ReadEntry(syncUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.EntryText = "kept"
+	if err := b.Write(e); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Sync(a, b, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.EntryStat(syncUUID); err != nil {
+		t.Error("expected edited entry to be restored to a")
+	}
+}
+
+func TestSyncPolicyError(t *testing.T) {
+	a, b, cleanup := syncedJournals(t)
+	defer cleanup()
+
+	editBoth(t, a, b)
+
+	policy := func(c *SyncConflict) (SyncResolution, error) {
+		return KeepA, errors.New("undecided")
+	}
+	report, err := Sync(a, b, &SyncOptions{Policy: policy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed[syncUUID] == nil {
+		t.Error("expected conflict to fail")
+	}
+
+	// Still unresolved, so the next sync asks again.
+	report, err = Sync(a, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Conflicts, []string{syncUUID}) {
+		t.Errorf("unexpected conflicts: %v", report.Conflicts)
+	}
+}
@@ -0,0 +1,165 @@
+package dayone
+
+import (
+	"sort"
+)
+
+// TagCount is a tag and the number of entries using it.
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// TagCounts returns every tag used in the journal along with
+// the number of entries using it, sorted by tag. Tags are
+// compared exactly, so "bjj" and "BJJ" are counted separately.
+func (j *Journal) TagCounts() ([]TagCount, error) {
+	counts := make(map[string]int)
+
+	err := j.Read(func(e *Entry, err error) error {
+		if e == nil {
+			return err
+		}
+
+		for _, tag := range uniqueTags(e.Tags) {
+			counts[tag]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []TagCount
+	for tag, count := range counts {
+		out = append(out, TagCount{Tag: tag, Count: count})
+	}
+	sort.Sort(byTag(out))
+
+	return out, nil
+}
+
+type byTag []TagCount
+
+func (s byTag) Len() int           { return len(s) }
+func (s byTag) Less(i, j int) bool { return s[i].Tag < s[j].Tag }
+func (s byTag) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// RenameTag renames the tag from to the tag to in every entry
+// that uses it and returns the uuids of the entries changed.
+// If dryRun is true no entries are written and the uuids
+// that would have changed are returned.
+func (j *Journal) RenameTag(from, to string, dryRun bool) ([]string, error) {
+	return j.MergeTags([]string{from}, to, dryRun)
+}
+
+// MergeTags replaces each of tags with the tag into in every
+// entry that uses them and returns the uuids of the entries changed.
+// If dryRun is true no entries are written and the uuids
+// that would have changed are returned.
+func (j *Journal) MergeTags(tags []string, into string, dryRun bool) ([]string, error) {
+	return j.rewriteTags(func(old []string) []string {
+		var out []string
+		for _, tag := range old {
+			if containsTag(tags, tag) {
+				tag = into
+			}
+			out = append(out, tag)
+		}
+		return uniqueTags(out)
+	}, dryRun)
+}
+
+// RemoveTag removes the tag from every entry that uses it and
+// returns the uuids of the entries changed.
+// If dryRun is true no entries are written and the uuids
+// that would have changed are returned.
+func (j *Journal) RemoveTag(tag string, dryRun bool) ([]string, error) {
+	return j.rewriteTags(func(old []string) []string {
+		var out []string
+		for _, t := range old {
+			if t != tag {
+				out = append(out, t)
+			}
+		}
+		return out
+	}, dryRun)
+}
+
+// rewriteTags applies fn to the tags of every entry and
+// writes the entries whose tags changed, holding the lock
+// throughout unless dryRun is true. Entries Read passes with
+// a consistency error are left alone.
+func (j *Journal) rewriteTags(fn func([]string) []string, dryRun bool) ([]string, error) {
+	if !dryRun {
+		unlock, err := j.lock()
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	var changed []string
+
+	err := j.Read(func(e *Entry, err error) error {
+		if e == nil {
+			return err
+		} else if err != nil {
+			// Its uuid can't be trusted, so saving it could
+			// write a different file. Check reports it.
+			return nil
+		}
+
+		tags := fn(e.Tags)
+		if equalTags(tags, e.Tags) {
+			return nil
+		}
+
+		changed = append(changed, e.UUID())
+		if dryRun {
+			return nil
+		}
+
+		// Save without syncing inline tags, which would put
+		// back a tag still in the text that was just renamed
+		// or removed.
+		e.Tags = tags
+		return j.save(e)
+	})
+	if err != nil {
+		return changed, err
+	}
+
+	return changed, nil
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueTags(tags []string) []string {
+	var out []string
+	for _, t := range tags {
+		if !containsTag(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func equalTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,185 @@
+package dayone
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTagCounts(t *testing.T) {
+	j := NewJournal("./test_journals/default")
+
+	counts, err := j.TagCounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []TagCount{
+		{Tag: "bjj", Count: 2},
+		{Tag: "fitness", Count: 2},
+	}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("actual: %v, expected: %v", counts, expected)
+	}
+}
+
+func TestRenameTag(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	changed, err := j.RenameTag("bjj", "jiu-jitsu", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changed) != 2 {
+		t.Errorf("expected 2 changed entries, got %v", changed)
+	}
+
+	e, err := j.ReadEntry("871D0F435D7B469C9429CD441A9E74B5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(e.Tags, []string{"jiu-jitsu", "fitness"}) {
+		t.Errorf("unexpected tags: %v", e.Tags)
+	}
+}
+
+func TestMergeTagsDryRun(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	changed, err := j.MergeTags([]string{"bjj", "fitness"}, "training", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changed) != 2 {
+		t.Errorf("expected 2 changed entries, got %v", changed)
+	}
+
+	e, err := j.ReadEntry("871D0F435D7B469C9429CD441A9E74B5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(e.Tags, []string{"bjj", "fitness"}) {
+		t.Errorf("dry run changed tags: %v", e.Tags)
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	_, err := j.MergeTags([]string{"bjj", "fitness"}, "training", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := j.ReadEntry("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(e.Tags, []string{"training"}) {
+		t.Errorf("unexpected tags: %v", e.Tags)
+	}
+}
+
+func TestRemoveTag(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	changed, err := j.RemoveTag("missing", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 0 {
+		t.Errorf("expected no changes, got %v", changed)
+	}
+
+	changed, err = j.RemoveTag("fitness", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 2 {
+		t.Errorf("expected 2 changed entries, got %v", changed)
+	}
+
+	e, err := j.ReadEntry("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(e.Tags, []string{"bjj"}) {
+		t.Errorf("unexpected tags: %v", e.Tags)
+	}
+}
+
+func TestRemoveTagWithSyncInlineTags(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanup()
+	j.SyncInlineTags = true
+
+	e := &Entry{EntryText: "rolled at #bjj", Tags: []string{"bjj", "fitness"}}
+	if err := j.Write(e); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := j.RemoveTag("bjj", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 1 {
+		t.Errorf("expected 1 changed entry, got %v", changed)
+	}
+
+	changed, err = j.RenameTag("fitness", "training", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 1 {
+		t.Errorf("expected 1 changed entry, got %v", changed)
+	}
+
+	e, err = j.ReadEntry(e.UUID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(e.Tags, []string{"training"}) {
+		t.Errorf("expected removed tag to stay removed, got %v", e.Tags)
+	}
+}
+
+func TestTagsWithMismatchedFile(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	copyEntryFile(t, j, copiedEntryUUID, false)
+
+	counts, err := j.TagCounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 2 || counts[0].Count != 3 {
+		t.Errorf("expected every file counted, got %v", counts)
+	}
+
+	// The copy and the file whose uuid it took are skipped.
+	changed, err := j.RenameTag("bjj", "jiu-jitsu", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changed, []string{"871D0F435D7B469C9429CD441A9E74B5"}) {
+		t.Errorf("unexpected changed entries: %v", changed)
+	}
+
+	uuids, err := j.entryUUIDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uuids) != 3 {
+		t.Errorf("expected 3 entry files, got %v", uuids)
+	}
+}
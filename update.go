@@ -0,0 +1,102 @@
+package dayone
+
+// FilterFunc selects the entries a bulk operation applies to.
+type FilterFunc func(e *Entry) bool
+
+// UpdateFunc changes an entry in place. Returning an error
+// marks the entry as failed and leaves it unwritten.
+type UpdateFunc func(e *Entry) error
+
+// EntryUpdate is the set of field changes made to one entry.
+type EntryUpdate struct {
+	UUID    string
+	Changes []FieldChange
+}
+
+// UpdateReport summarizes the result of Journal.Update.
+type UpdateReport struct {
+	// Modified are the entries that changed (or would
+	// have changed, for a dry run).
+	Modified []EntryUpdate
+
+	// Skipped are the uuids of entries that didn't match
+	// the filter or that fn left unchanged.
+	Skipped []string
+
+	// Failed are the entries that couldn't be read,
+	// updated or written, keyed by uuid.
+	Failed map[string]error
+}
+
+// Update applies fn to every entry matching filter and
+// writes the entries that changed. A nil filter matches
+// every entry. If dryRun is true nothing is written but
+// the report still lists the changes that would be made.
+//
+// Entries are written one at a time, each atomically, with
+// the journal locked for the whole update.
+// A failure on one entry is recorded in the report and
+// doesn't stop the rest from being updated.
+func (j *Journal) Update(filter FilterFunc, fn UpdateFunc, dryRun bool) (*UpdateReport, error) {
+	if !dryRun {
+		unlock, err := j.lock()
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	uuids, err := j.entryUUIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &UpdateReport{
+		Failed: make(map[string]error),
+	}
+
+	for _, uuid := range uuids {
+		e, err := j.ReadEntry(uuid)
+		if err != nil {
+			report.Failed[uuid] = err
+			continue
+		}
+
+		if filter != nil && !filter(e) {
+			report.Skipped = append(report.Skipped, uuid)
+			continue
+		}
+
+		updated := e.clone()
+		if err := fn(updated); err != nil {
+			report.Failed[uuid] = err
+			continue
+		}
+
+		// Merge inline tags before diffing so the changes
+		// include the tags that will be written.
+		if j.SyncInlineTags {
+			updated.MergeInlineTags()
+		}
+
+		changes := DiffEntries(e, updated)
+		if len(changes) == 0 {
+			report.Skipped = append(report.Skipped, uuid)
+			continue
+		}
+
+		if !dryRun {
+			if err := j.save(updated); err != nil {
+				report.Failed[uuid] = err
+				continue
+			}
+		}
+
+		report.Modified = append(report.Modified, EntryUpdate{
+			UUID:    uuid,
+			Changes: changes,
+		})
+	}
+
+	return report, nil
+}
@@ -0,0 +1,122 @@
+package dayone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUpdate(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	report, err := j.Update(func(e *Entry) bool {
+		return e.UUID() == "FF755C6D7D9B4A5FBC4E41C07D622C65"
+	}, func(e *Entry) error {
+		e.TimeZone = "America/New_York"
+		e.Activity = ""
+		return nil
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Modified) != 1 {
+		t.Fatalf("expected 1 modified entry, got %v", report.Modified)
+	}
+
+	if len(report.Modified[0].Changes) != 2 {
+		t.Errorf("expected 2 changes, got %v", report.Modified[0].Changes)
+	}
+
+	if len(report.Skipped) != 1 || report.Skipped[0] != "871D0F435D7B469C9429CD441A9E74B5" {
+		t.Errorf("unexpected skipped: %v", report.Skipped)
+	}
+
+	e, err := j.ReadEntry("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.TimeZone != "America/New_York" || e.Activity != "" {
+		t.Error("entry wasn't updated")
+	}
+}
+
+func TestUpdateDryRun(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	report, err := j.Update(nil, func(e *Entry) error {
+		e.Starred = false
+		return nil
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Modified) != 2 {
+		t.Errorf("expected 2 modified entries, got %v", report.Modified)
+	}
+
+	e, err := j.ReadEntry("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !e.Starred {
+		t.Error("dry run wrote entry")
+	}
+}
+
+func TestUpdateRecordsFailures(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	boom := errors.New("boom")
+	report, err := j.Update(nil, func(e *Entry) error {
+		return boom
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Failed) != 2 || report.Failed["871D0F435D7B469C9429CD441A9E74B5"] != boom {
+		t.Errorf("unexpected failures: %v", report.Failed)
+	}
+}
+
+func TestUpdateReportsSyncedInlineTags(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+	j.SyncInlineTags = true
+
+	report, err := j.Update(func(e *Entry) bool {
+		return e.UUID() == "FF755C6D7D9B4A5FBC4E41C07D622C65"
+	}, func(e *Entry) error {
+		e.EntryText += " #judo"
+		return nil
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Modified) != 1 {
+		t.Fatalf("expected 1 modified entry, got %v", report.Modified)
+	}
+
+	var fields []string
+	for _, c := range report.Modified[0].Changes {
+		fields = append(fields, c.Field)
+	}
+	if len(fields) != 2 || !containsTag(fields, "EntryText") || !containsTag(fields, "Tags") {
+		t.Errorf("expected text and tag changes, got %v", report.Modified[0].Changes)
+	}
+
+	e, err := j.ReadEntry("FF755C6D7D9B4A5FBC4E41C07D622C65")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsTag(e.Tags, "judo") {
+		t.Errorf("expected judo tag to be written, got %v", e.Tags)
+	}
+}
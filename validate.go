@@ -0,0 +1,152 @@
+package dayone
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9A-F]{32}$`)
+
+// FieldError is a problem with a single entry field.
+// Field is the dotted path to the field, as in FieldChange.
+type FieldError struct {
+	Field   string
+	Message string
+
+	// Warning is true for oddities Day One itself writes,
+	// which don't stop the entry being written.
+	Warning bool
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+// ValidationError lists every problem found when validating
+// an entry. Writes refuse entries with any problem that
+// isn't a warning.
+type ValidationError struct {
+	Problems []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var msgs []string
+	for _, p := range e.Problems {
+		msgs = append(msgs, p.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// validate checks the entry and returns a *ValidationError
+// listing all of its problems, or nil if it is valid.
+func (e *Entry) validate() error {
+	v := &validator{}
+
+	switch {
+	case e.uuid == "":
+		v.add("UUID", "missing uuid")
+	case !uuidPattern.MatchString(e.uuid):
+		v.add("UUID", "invalid uuid "+e.uuid+": must be 32 uppercase hex digits")
+	}
+
+	if e.CreationDate.IsZero() {
+		v.add("CreationDate", "missing creation date")
+	}
+
+	if e.TimeZone != "" {
+		if _, err := time.LoadLocation(e.TimeZone); err != nil {
+			v.add("TimeZone", "unknown time zone "+e.TimeZone)
+		}
+	}
+
+	if e.IgnoreStepCount && e.StepCount != 0 {
+		v.warn("StepCount", "step count set but ignored")
+	}
+
+	if e.Location != nil {
+		v.coordinate("Location", &e.Location.Coordinate)
+		if r := e.Location.Region; r != nil {
+			if r.Center != nil {
+				v.coordinate("Location.Region.Center", r.Center)
+			}
+			if r.Radius < 0 {
+				v.add("Location.Region.Radius", fmt.Sprintf("negative region radius %v", r.Radius))
+			}
+		}
+	}
+
+	if e.Weather != nil {
+		v.weather(e.Weather)
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// validateWrite is validate without the warnings, returning
+// nil if the entry can be written.
+func (e *Entry) validateWrite() error {
+	err, ok := e.validate().(*ValidationError)
+	if !ok {
+		return nil
+	}
+
+	var problems []*FieldError
+	for _, p := range err.Problems {
+		if !p.Warning {
+			problems = append(problems, p)
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+type validator struct {
+	problems []*FieldError
+}
+
+func (v *validator) add(field, msg string) {
+	v.problems = append(v.problems, &FieldError{Field: field, Message: msg})
+}
+
+func (v *validator) warn(field, msg string) {
+	v.problems = append(v.problems, &FieldError{Field: field, Message: msg, Warning: true})
+}
+
+func (v *validator) coordinate(field string, c *Coordinate) {
+	if c.Latitude < -90 || c.Latitude > 90 {
+		v.add(field+".Latitude", fmt.Sprintf("latitude %v out of range", c.Latitude))
+	}
+	if c.Longitude < -180 || c.Longitude > 180 {
+		v.add(field+".Longitude", fmt.Sprintf("longitude %v out of range", c.Longitude))
+	}
+}
+
+func (v *validator) weather(w *Weather) {
+	c, cerr := strconv.ParseFloat(w.Celsius, 64)
+	if w.Celsius != "" && cerr != nil {
+		v.add("Weather.Celsius", "invalid celsius "+w.Celsius)
+	}
+
+	f, ferr := strconv.ParseFloat(w.Fahrenheit, 64)
+	if w.Fahrenheit != "" && ferr != nil {
+		v.add("Weather.Fahrenheit", "invalid fahrenheit "+w.Fahrenheit)
+	}
+
+	// Day One stores whole degrees so allow for rounding.
+	if cerr == nil && ferr == nil && math.Abs(c*9/5+32-f) > 1.5 {
+		v.add("Weather.Fahrenheit", fmt.Sprintf("%s°F doesn't match %s°C", w.Fahrenheit, w.Celsius))
+	}
+
+	if !w.SunriseDate.IsZero() && !w.SunsetDate.IsZero() && w.SunriseDate.After(w.SunsetDate) {
+		v.add("Weather.SunriseDate", "sunrise is after sunset")
+	}
+}
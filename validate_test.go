@@ -0,0 +1,148 @@
+package dayone
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func validationFields(t *testing.T, e *Entry) []string {
+	err := e.validate()
+	if err == nil {
+		return nil
+	}
+
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a validation error, got %T", err)
+	}
+
+	var fields []string
+	for _, p := range verr.Problems {
+		fields = append(fields, p.Field)
+	}
+	return fields
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	sunset := time.Date(2014, 9, 24, 0, 26, 11, 0, time.UTC)
+
+	e := &Entry{
+		uuid:            "ff755c6d",
+		TimeZone:        "Nowhere/Special",
+		IgnoreStepCount: true,
+		StepCount:       1043,
+		Location: &Location{
+			Coordinate: Coordinate{Latitude: 91, Longitude: -181},
+			Region: &Region{
+				Center: &Coordinate{Latitude: -91},
+				Radius: -1,
+			},
+		},
+		Weather: &Weather{
+			Celsius:     "24",
+			Fahrenheit:  "40",
+			SunriseDate: sunset.Add(time.Hour),
+			SunsetDate:  sunset,
+		},
+	}
+
+	expected := []string{
+		"UUID",
+		"CreationDate",
+		"TimeZone",
+		"StepCount",
+		"Location.Latitude",
+		"Location.Longitude",
+		"Location.Region.Center.Latitude",
+		"Location.Region.Radius",
+		"Weather.Fahrenheit",
+		"Weather.SunriseDate",
+	}
+
+	fields := validationFields(t, e)
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("actual: %v, expected: %v", fields, expected)
+	}
+}
+
+func TestValidateWeatherTemperatures(t *testing.T) {
+	e := newEntry()
+
+	e.Weather = &Weather{Celsius: "hot", Fahrenheit: "75"}
+	if fields := validationFields(t, e); !reflect.DeepEqual(fields, []string{"Weather.Celsius"}) {
+		t.Errorf("unexpected problems: %v", fields)
+	}
+
+	// 24°C is 75.2°F, which Day One rounds.
+	e.Weather = &Weather{Celsius: "24", Fahrenheit: "75"}
+	if fields := validationFields(t, e); fields != nil {
+		t.Errorf("unexpected problems: %v", fields)
+	}
+}
+
+func TestValidateExistingEntries(t *testing.T) {
+	j := NewJournal("./test_journals/default")
+
+	err := j.Read(func(e *Entry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Day One's own entries may have warnings.
+		return e.validateWrite()
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWriteRefusesInvalidEntry(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanup()
+
+	e := &Entry{TimeZone: "Nowhere/Special"}
+	err := j.Write(e)
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "Nowhere/Special") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, err := j.EntryStat(e.UUID()); err == nil {
+		t.Error("invalid entry was written")
+	}
+}
+
+func TestWritePhotoRefusesInvalidUUID(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanup()
+
+	if err := j.WritePhoto("../escape", strings.NewReader("jpeg")); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestValidateWarnsAboutIgnoredStepCount(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/empty_with_dirs")
+	defer cleanup()
+
+	e := newEntry()
+	e.IgnoreStepCount = true
+	e.StepCount = 1043
+
+	err, ok := e.validate().(*ValidationError)
+	if !ok || len(err.Problems) != 1 {
+		t.Fatalf("expected one problem, got %v", e.validate())
+	}
+	if p := err.Problems[0]; p.Field != "StepCount" || !p.Warning {
+		t.Errorf("expected a StepCount warning, got %+v", p)
+	}
+
+	// Day One writes entries like this, so they aren't refused.
+	if err := j.Write(e); err != nil {
+		t.Error(err)
+	}
+}
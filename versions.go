@@ -0,0 +1,407 @@
+package dayone
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/juju/errgo"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// versionsDirName is the hidden dir inside the journal where
+// versions are kept. Its objects dir holds every version of
+// every entry and photo file named by the SHA-1 of its content,
+// and its log dir holds a JSON Lines history for each uuid.
+const versionsDirName = ".versions"
+
+// hashPattern matches the SHA-1 hashes naming version objects.
+var hashPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// ErrNoVersion is returned when a version's content is missing
+// from the versions dir.
+var ErrNoVersion = errors.New("version not found")
+
+// Version is the state of an entry and its photo from Time
+// until the next version. Entry and Photo are the SHA-1 of the
+// file contents, or empty if the file didn't exist.
+//
+// An entry created with KeepVersions set starts with a Version
+// at the zero Time with no Entry, since it never existed before.
+type Version struct {
+	Time  time.Time `json:"time"`
+	Entry string    `json:"entry,omitempty"`
+	Photo string    `json:"photo,omitempty"`
+}
+
+// Deleted reports whether the entry didn't exist in this version.
+func (v Version) Deleted() bool {
+	return v.Entry == ""
+}
+
+func (j *Journal) getVersionsDir() string {
+	return filepath.Join(j.dir, versionsDirName)
+}
+
+// objectPath returns the path of the object hash, checking
+// the hash so a bad Version can't name a file outside the
+// objects dir.
+func (j *Journal) objectPath(hash string) (string, error) {
+	if !hashPattern.MatchString(hash) {
+		return "", errors.New("invalid version hash " + hash)
+	}
+	return filepath.Join(j.getVersionsDir(), "objects", hash[:2], hash[2:]), nil
+}
+
+func (j *Journal) versionLogPath(uuid string) string {
+	return filepath.Join(j.getVersionsDir(), "log", uuid+".jsonl")
+}
+
+// versioned runs fn, which changes the files for uuid, and
+// records the versions before and after when KeepVersions is
+// set. The caller must hold the lock.
+func (j *Journal) versioned(uuid string, fn func() error) error {
+	if !j.KeepVersions {
+		return fn()
+	}
+
+	if err := j.recordVersion(uuid); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	return j.recordVersion(uuid)
+}
+
+// recordVersion stores the current entry and photo for uuid
+// and appends them to its log unless they match the last
+// version. That also catches changes made outside this package.
+func (j *Journal) recordVersion(uuid string) error {
+	versions, err := j.Versions(uuid)
+	if err != nil {
+		return err
+	}
+
+	var v Version
+	if v.Entry, err = j.storeObject(filepath.Join(j.getEntriesDir(), uuid+entryExt), &v.Time); err != nil {
+		return err
+	}
+	if v.Photo, err = j.storeObject(filepath.Join(j.getPhotosDir(), uuid+photoExt), &v.Time); err != nil {
+		return err
+	}
+
+	if len(versions) > 0 {
+		last := versions[len(versions)-1]
+		if last.Entry == v.Entry && last.Photo == v.Photo {
+			return nil
+		}
+	}
+
+	switch {
+	case len(versions) == 0 && v.Deleted():
+		// A new entry, so leave the zero Time.
+	case v.Time.IsZero():
+		v.Time = time.Now().UTC()
+	}
+
+	return j.appendVersion(uuid, v)
+}
+
+// storeObject copies the file at path into the objects dir and
+// returns its hash, or "" if it doesn't exist. mtime is set to
+// the file's mtime if that is later.
+func (j *Journal) storeObject(path string, mtime *time.Time) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", errgo.Mask(err)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", errgo.Mask(err)
+	}
+	if fi.ModTime().After(*mtime) {
+		*mtime = fi.ModTime().UTC()
+	}
+
+	sum := sha1.Sum(b)
+	hash := hex.EncodeToString(sum[:])
+
+	target, err := j.objectPath(hash)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(target); err == nil {
+		return hash, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", errgo.Mask(err)
+	}
+	err = writeFileAtomic(target, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+func (j *Journal) appendVersion(uuid string, v Version) error {
+	path := j.versionLogPath(uuid)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errgo.Mask(err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return errgo.Mask(err)
+	}
+
+	if err := json.NewEncoder(f).Encode(v); err != nil {
+		f.Close()
+		return errgo.Mask(err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return errgo.Mask(err)
+	}
+	if err := f.Close(); err != nil {
+		return errgo.Mask(err)
+	}
+	return nil
+}
+
+// Versions returns the recorded versions of the entry, oldest
+// first. Entries never written with KeepVersions set have none.
+func (j *Journal) Versions(uuid string) ([]Version, error) {
+	if !uuidPattern.MatchString(uuid) {
+		return nil, errors.New("invalid uuid " + uuid)
+	}
+
+	f, err := os.Open(j.versionLogPath(uuid))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	defer f.Close()
+
+	var versions []Version
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var v Version
+		if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+			// A torn write from a crash only loses its own line.
+			continue
+		}
+		versions = append(versions, v)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errgo.Mask(err)
+	}
+
+	return versions, nil
+}
+
+// VersionEntry returns the entry as it was in version v,
+// or nil if it didn't exist then.
+func (j *Journal) VersionEntry(uuid string, v Version) (*Entry, error) {
+	if v.Deleted() {
+		return nil, nil
+	}
+
+	path, err := j.objectPath(v.Entry)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := readEntryFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNoVersion
+	} else if err != nil {
+		return nil, err
+	}
+	e.forgetStat()
+	j.resolveUUID(e, uuid)
+	return e, nil
+}
+
+// DiffVersions returns the changes to the entry between
+// versions a and b. A change to the photo is reported as a
+// "Photo" field with the SHA-1 of each file.
+func (j *Journal) DiffVersions(uuid string, a, b Version) ([]FieldChange, error) {
+	ea, err := j.VersionEntry(uuid, a)
+	if err != nil {
+		return nil, err
+	}
+	eb, err := j.VersionEntry(uuid, b)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := DiffEntries(ea, eb)
+	if a.Photo != b.Photo {
+		changes = append(changes, FieldChange{Field: "Photo", Old: a.Photo, New: b.Photo})
+	}
+	return changes, nil
+}
+
+// RestoreVersion puts the entry and its photo back the way
+// they were in version v, deleting them if v is Deleted.
+// With KeepVersions set the restore is itself a new version.
+func (j *Journal) RestoreVersion(uuid string, v Version) error {
+	if !uuidPattern.MatchString(uuid) {
+		return errors.New("invalid uuid " + uuid)
+	}
+
+	unlock, err := j.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return j.restore(uuid, v)
+}
+
+// restore is RestoreVersion for a caller holding the lock.
+func (j *Journal) restore(uuid string, v Version) error {
+	// Check both hashes first so a bad photo hash doesn't
+	// leave the entry restored without its photo.
+	for _, hash := range []string{v.Entry, v.Photo} {
+		if hash == "" {
+			continue
+		}
+		if _, err := j.objectPath(hash); err != nil {
+			return err
+		}
+	}
+
+	return j.versioned(uuid, func() error {
+		if err := j.restoreObject(v.Entry, filepath.Join(j.getEntriesDir(), uuid+entryExt)); err != nil {
+			return err
+		}
+		return j.restoreObject(v.Photo, filepath.Join(j.getPhotosDir(), uuid+photoExt))
+	})
+}
+
+// restoreObject replaces the file at path with the object
+// hash, or removes it if hash is empty.
+func (j *Journal) restoreObject(hash, path string) error {
+	if hash == "" {
+		return removeFiles([]string{path})
+	}
+
+	object, err := j.objectPath(hash)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(object)
+	if os.IsNotExist(err) {
+		return ErrNoVersion
+	} else if err != nil {
+		return errgo.Mask(err)
+	}
+	defer f.Close()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errgo.Mask(err)
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		_, err := io.Copy(w, f)
+		return err
+	})
+}
+
+// RestoreJournal puts every entry with recorded versions back
+// the way it was at time t and returns the uuids it changed.
+// Entries created after t are deleted. Entries whose history
+// doesn't reach back to t are left alone.
+func (j *Journal) RestoreJournal(t time.Time) ([]string, error) {
+	unlock, err := j.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	files, err := ioutil.ReadDir(filepath.Join(j.getVersionsDir(), "log"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errgo.Mask(err)
+	}
+
+	var restored []string
+	for _, f := range files {
+		if filepath.Ext(f.Name()) != ".jsonl" {
+			continue
+		}
+		uuid := f.Name()[:len(f.Name())-len(".jsonl")]
+		if !uuidPattern.MatchString(uuid) {
+			continue
+		}
+
+		versions, err := j.Versions(uuid)
+		if err != nil {
+			return restored, err
+		}
+
+		var at *Version
+		for i := range versions {
+			if !versions[i].Time.After(t) {
+				at = &versions[i]
+			}
+		}
+		if at == nil {
+			continue
+		}
+
+		current, err := j.currentVersion(uuid)
+		if err != nil {
+			return restored, err
+		}
+		if current.Entry == at.Entry && current.Photo == at.Photo {
+			continue
+		}
+
+		if err := j.restore(uuid, *at); err != nil {
+			return restored, err
+		}
+		restored = append(restored, uuid)
+	}
+
+	return restored, nil
+}
+
+// currentVersion hashes the entry and photo files for uuid
+// without storing them.
+func (j *Journal) currentVersion(uuid string) (Version, error) {
+	var v Version
+	var err error
+
+	v.Entry, err = hashFile(filepath.Join(j.getEntriesDir(), uuid+entryExt))
+	if err != nil && !os.IsNotExist(err) {
+		return v, err
+	}
+	v.Photo, err = hashFile(filepath.Join(j.getPhotosDir(), uuid+photoExt))
+	if err != nil && !os.IsNotExist(err) {
+		return v, err
+	}
+	return v, nil
+}
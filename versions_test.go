@@ -0,0 +1,299 @@
+package dayone
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVersionsOff(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	e, err := j.ReadEntry(syncUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.EntryText = "edited"
+	if err := j.Write(e); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := j.Versions(syncUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 0 {
+		t.Error("expected no versions")
+	}
+	if _, err := os.Stat(j.getVersionsDir()); !os.IsNotExist(err) {
+		t.Error("expected no versions dir")
+	}
+}
+
+func TestVersionsEditAndRestore(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+	j.KeepVersions = true
+
+	e, err := j.ReadEntry(syncUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := e.EntryText
+
+	e.EntryText = "edited"
+	if err := j.Write(e); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := j.Versions(syncUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 versions, got %d", len(versions))
+	}
+
+	changes, err := j.DiffVersions(syncUUID, versions[0], versions[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []FieldChange{{Field: "EntryText", Old: original, New: "edited"}}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("unexpected changes: %v", changes)
+	}
+
+	if err := j.RestoreVersion(syncUUID, versions[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err = j.ReadEntry(syncUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.EntryText != original {
+		t.Error("expected original text to be restored")
+	}
+
+	versions, err = j.Versions(syncUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 || versions[2].Entry != versions[0].Entry {
+		t.Error("expected the restore to be recorded as a version")
+	}
+}
+
+func TestVersionsDeduplicated(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+	j.KeepVersions = true
+
+	e, err := j.ReadEntry(syncUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Write(e); err != nil {
+		t.Fatal(err)
+	}
+	before, err := j.Versions(syncUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := j.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	after, err := j.Versions(syncUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(before) {
+		t.Errorf("expected unchanged writes to add no versions, got %d", len(after)-len(before))
+	}
+}
+
+func TestVersionsPhotoAndDelete(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+	j.KeepVersions = true
+
+	if err := j.WritePhoto(photoUUID, strings.NewReader("not really a jpeg")); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Delete(photoUUID); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := j.Versions(photoUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("expected 3 versions, got %d", len(versions))
+	}
+	if versions[0].Photo == versions[1].Photo || !versions[2].Deleted() {
+		t.Errorf("unexpected versions: %v", versions)
+	}
+
+	changes, err := j.DiffVersions(photoUUID, versions[0], versions[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Field != "Photo" {
+		t.Errorf("unexpected changes: %v", changes)
+	}
+
+	if err := j.RestoreVersion(photoUUID, versions[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.EntryStat(photoUUID); err != nil {
+		t.Error("expected entry to be restored")
+	}
+	if _, err := j.PhotoStat(photoUUID); err != nil {
+		t.Error("expected photo to be restored")
+	}
+}
+
+func TestRestoreJournal(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+	j.KeepVersions = true
+
+	e, err := j.ReadEntry(syncUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := e.EntryText
+
+	// Record the current state before the snapshot time.
+	if err := j.Write(e); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	snapshot := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	e.EntryText = "edited"
+	if err := j.Write(e); err != nil {
+		t.Fatal(err)
+	}
+	created := &Entry{EntryText: "new"}
+	if err := j.Write(created); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := j.RestoreJournal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored) != 2 {
+		t.Errorf("unexpected restored: %v", restored)
+	}
+
+	e, err = j.ReadEntry(syncUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.EntryText != original {
+		t.Error("expected original text to be restored")
+	}
+	if _, err := j.EntryStat(created.UUID()); !os.IsNotExist(err) {
+		t.Error("expected new entry to be deleted")
+	}
+
+	// The other entry has no history so is left alone.
+	if _, err := j.EntryStat(photoUUID); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVersionsRestoreResolvedConflict(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+	j.KeepVersions = true
+
+	addConflictedCopy(t, j)
+
+	conflicts, err := j.Conflicts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.ResolveConflict(conflicts[0], KeepNewest); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := j.Versions(syncUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 versions, got %d", len(versions))
+	}
+
+	if err := j.RestoreVersion(syncUUID, versions[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := j.ReadEntry(syncUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.EntryText != "#title line\n\nbody line" {
+		t.Errorf("expected the replaced entry to be restored, got %q", e.EntryText)
+	}
+}
+
+func TestVersionsRestoreQuarantined(t *testing.T) {
+	j, cleanup := brokenJournal(t)
+	defer cleanup()
+	j.KeepVersions = true
+
+	const uuid = "11111111111111111111111111111111"
+	if _, err := j.Check(&CheckOptions{Repair: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := j.Versions(uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || !versions[1].Deleted() {
+		t.Fatalf("expected the quarantine to be recorded, got %+v", versions)
+	}
+
+	if err := j.RestoreVersion(uuid, versions[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.EntryStat(uuid); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVersionsRejectInvalid(t *testing.T) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+	j.KeepVersions = true
+
+	for _, v := range []Version{{Entry: "a"}, {Entry: "../../entries/x"}} {
+		if _, err := j.VersionEntry(syncUUID, v); err == nil {
+			t.Errorf("%+v: expected an error", v)
+		}
+		if err := j.RestoreVersion(syncUUID, v); err == nil {
+			t.Errorf("%+v: expected an error", v)
+		}
+	}
+
+	if _, err := j.Versions("../escape"); err == nil {
+		t.Error("expected an error for an invalid uuid")
+	}
+	if err := j.RestoreVersion("../escape", Version{}); err == nil {
+		t.Error("expected an error for an invalid uuid")
+	}
+}
@@ -0,0 +1,388 @@
+package dayone
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventType is the kind of change reported by a Watcher.
+type EventType int
+
+const (
+	EntryCreated EventType = iota
+	EntryModified
+	EntryDeleted
+
+	// PhotoAdded is also sent when an existing photo is replaced.
+	PhotoAdded
+	PhotoRemoved
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EntryCreated:
+		return "entry created"
+	case EntryModified:
+		return "entry modified"
+	case EntryDeleted:
+		return "entry deleted"
+	case PhotoAdded:
+		return "photo added"
+	case PhotoRemoved:
+		return "photo removed"
+	}
+	return "unknown"
+}
+
+// Event is a change to an entry or photo in a watched journal.
+type Event struct {
+	Type EventType
+	UUID string
+}
+
+// WatchOptions configures Journal.Watch.
+// The zero value uses the defaults.
+type WatchOptions struct {
+	// Debounce is how long a file must go unchanged before
+	// its event is sent, so a burst of writes from a sync
+	// collapses into one event per uuid. Defaults to 500ms.
+	Debounce time.Duration
+
+	// Poll forces the polling watcher even when native
+	// file system notifications are available.
+	Poll bool
+
+	// PollInterval is how often the polling watcher checks
+	// the journal for changes. Defaults to 2s.
+	PollInterval time.Duration
+}
+
+// Watcher streams change events for a journal.
+// Create one with Journal.Watch and Close it when done.
+type Watcher struct {
+	// Events receives a debounced event for each changed
+	// entry or photo. It is closed by Close.
+	Events <-chan Event
+
+	// Errors receives errors hit while watching.
+	// It is closed by Close.
+	Errors <-chan error
+
+	j       *Journal
+	opts    WatchOptions
+	events  chan Event
+	errors  chan error
+	changed chan fileKey
+	done    chan struct{}
+	closers []io.Closer
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// fileKey identifies an entry or photo file. The zero
+// fileKey asks the watcher to rescan the whole journal.
+type fileKey struct {
+	uuid  string
+	photo bool
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watch starts watching the journal's entries and photos
+// dirs for changes. Native notifications (inotify) are
+// used where available, otherwise the dirs are polled
+// using the mtimes from EntryStat and PhotoStat.
+// opts may be nil.
+func (j *Journal) Watch(opts *WatchOptions) (*Watcher, error) {
+	w := &Watcher{
+		j:       j,
+		events:  make(chan Event),
+		errors:  make(chan error, 1),
+		changed: make(chan fileKey, 64),
+		done:    make(chan struct{}),
+	}
+	w.Events = w.events
+	w.Errors = w.errors
+
+	if opts != nil {
+		w.opts = *opts
+	}
+	if w.opts.Debounce <= 0 {
+		w.opts.Debounce = 500 * time.Millisecond
+	}
+	if w.opts.PollInterval <= 0 {
+		w.opts.PollInterval = 2 * time.Second
+	}
+
+	snapshot, err := j.scanFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	if w.opts.Poll || w.startNative() != nil {
+		// Copy so the poller and the loop don't share the map.
+		prev := make(map[fileKey]fileState, len(snapshot))
+		for k, v := range snapshot {
+			prev[k] = v
+		}
+
+		w.wg.Add(1)
+		go w.poll(prev)
+	}
+
+	w.wg.Add(1)
+	go w.loop(snapshot)
+
+	return w, nil
+}
+
+// Close stops the watcher and closes its channels.
+func (w *Watcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		for _, c := range w.closers {
+			if cerr := c.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+		w.wg.Wait()
+		close(w.events)
+		close(w.errors)
+	})
+	return err
+}
+
+// notify queues k to be checked once the debounce
+// period has passed.
+func (w *Watcher) notify(k fileKey) {
+	select {
+	case w.changed <- k:
+	case <-w.done:
+	}
+}
+
+func (w *Watcher) sendError(err error) {
+	select {
+	case w.errors <- err:
+	case <-w.done:
+	}
+}
+
+// loop collects changed files until they settle and then
+// compares them to snapshot to decide which events to send.
+func (w *Watcher) loop(snapshot map[fileKey]fileState) {
+	defer w.wg.Done()
+
+	pending := make(map[fileKey]bool)
+	timer := time.NewTimer(w.opts.Debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			timer.Stop()
+			return
+		case k := <-w.changed:
+			pending[k] = true
+			timer.Reset(w.opts.Debounce)
+		case <-timer.C:
+			if pending[fileKey{}] {
+				delete(pending, fileKey{})
+				if err := w.addAll(pending, snapshot); err != nil {
+					w.sendError(err)
+				}
+			}
+
+			for _, ev := range w.settle(pending, snapshot) {
+				select {
+				case w.events <- ev:
+				case <-w.done:
+					return
+				}
+			}
+			pending = make(map[fileKey]bool)
+		}
+	}
+}
+
+// addAll adds every known and current file to pending.
+func (w *Watcher) addAll(pending map[fileKey]bool, snapshot map[fileKey]fileState) error {
+	current, err := w.j.scanFiles()
+	if err != nil {
+		return err
+	}
+	for k := range snapshot {
+		pending[k] = true
+	}
+	for k := range current {
+		pending[k] = true
+	}
+	return nil
+}
+
+// settle stats each pending file, updates snapshot and
+// returns the events for the files that really changed.
+func (w *Watcher) settle(pending map[fileKey]bool, snapshot map[fileKey]fileState) []Event {
+	var keys []fileKey
+	for k := range pending {
+		keys = append(keys, k)
+	}
+	sort.Sort(byFileKey(keys))
+
+	var events []Event
+	for _, k := range keys {
+		var fi os.FileInfo
+		var err error
+		if k.photo {
+			fi, err = w.j.PhotoStat(k.uuid)
+		} else {
+			fi, err = w.j.EntryStat(k.uuid)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			w.sendError(err)
+			continue
+		}
+
+		old, existed := snapshot[k]
+		exists := err == nil
+
+		var t EventType
+		switch {
+		case exists && !existed:
+			t = EntryCreated
+		case !exists && existed:
+			t = EntryDeleted
+		case exists && existed && !old.equal(fi):
+			t = EntryModified
+		default:
+			continue
+		}
+
+		if exists {
+			snapshot[k] = fileState{fi.ModTime(), fi.Size()}
+		} else {
+			delete(snapshot, k)
+		}
+
+		if k.photo {
+			if t == EntryDeleted {
+				t = PhotoRemoved
+			} else {
+				t = PhotoAdded
+			}
+		}
+		events = append(events, Event{Type: t, UUID: k.uuid})
+	}
+
+	return events
+}
+
+// poll rescans the journal every PollInterval and
+// notifies the loop of any files that changed.
+func (w *Watcher) poll(prev map[fileKey]fileState) {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		current, err := w.j.scanFiles()
+		if err != nil {
+			w.sendError(err)
+			continue
+		}
+
+		for k, s := range current {
+			if old, ok := prev[k]; !ok || !old.same(s) {
+				w.notify(k)
+			}
+		}
+		for k := range prev {
+			if _, ok := current[k]; !ok {
+				w.notify(k)
+			}
+		}
+		prev = current
+	}
+}
+
+// scanFiles stats every entry and photo in the journal.
+// Missing entries or photos dirs are treated as empty.
+func (j *Journal) scanFiles() (map[fileKey]fileState, error) {
+	files := make(map[fileKey]fileState)
+
+	entries, err := j.entryUUIDs()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, uuid := range entries {
+		fi, err := j.EntryStat(uuid)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		files[fileKey{uuid: uuid}] = fileState{fi.ModTime(), fi.Size()}
+	}
+
+	photos, err := j.photoUUIDs()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, uuid := range photos {
+		fi, err := j.PhotoStat(uuid)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		files[fileKey{uuid: uuid, photo: true}] = fileState{fi.ModTime(), fi.Size()}
+	}
+
+	return files, nil
+}
+
+// fileKeyFor returns the key for the file name found in
+// the entries dir, or the photos dir when photo is true.
+func fileKeyFor(name string, photo bool) (fileKey, bool) {
+	if photo && !isPhotoFile(name) || !photo && !isEntryFile(name) {
+		return fileKey{}, false
+	}
+
+	uuid := strings.TrimSuffix(name, filepath.Ext(name))
+	return fileKey{uuid: uuid, photo: photo}, true
+}
+
+func (s fileState) equal(fi os.FileInfo) bool {
+	return s.same(fileState{fi.ModTime(), fi.Size()})
+}
+
+func (s fileState) same(o fileState) bool {
+	return s.modTime.Equal(o.modTime) && s.size == o.size
+}
+
+type byFileKey []fileKey
+
+func (s byFileKey) Len() int { return len(s) }
+func (s byFileKey) Less(i, j int) bool {
+	if s[i].uuid != s[j].uuid {
+		return s[i].uuid < s[j].uuid
+	}
+	return !s[i].photo && s[j].photo
+}
+func (s byFileKey) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
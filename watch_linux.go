@@ -0,0 +1,81 @@
+package dayone
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY |
+	syscall.IN_ATTRIB | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// startNative watches the entries and photos dirs with inotify.
+func (w *Watcher) startNative() error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return err
+	}
+
+	// A non-blocking fd wrapped in an os.File uses the runtime
+	// poller, so Close unblocks a pending Read.
+	f := os.NewFile(uintptr(fd), "inotify")
+
+	dirs := map[int32]bool{} // watch descriptor -> is photos dir
+	for dir, photo := range map[string]bool{
+		w.j.getEntriesDir(): false,
+		w.j.getPhotosDir():  true,
+	} {
+		wd, err := syscall.InotifyAddWatch(fd, dir, inotifyMask)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		dirs[int32(wd)] = photo
+	}
+
+	w.closers = append(w.closers, f)
+	w.wg.Add(1)
+	go w.readInotify(f, dirs)
+
+	return nil
+}
+
+func (w *Watcher) readInotify(f *os.File, dirs map[int32]bool) {
+	defer w.wg.Done()
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			select {
+			case <-w.done:
+			default:
+				w.sendError(err)
+			}
+			return
+		}
+
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			start := off + syscall.SizeofInotifyEvent
+			off = start + int(ev.Len)
+
+			if ev.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				// Events were dropped, so check everything.
+				w.notify(fileKey{})
+				continue
+			}
+
+			photo, ok := dirs[ev.Wd]
+			if !ok {
+				continue
+			}
+
+			name := strings.TrimRight(string(buf[start:off]), "\x00")
+			if k, ok := fileKeyFor(name, photo); ok {
+				w.notify(k)
+			}
+		}
+	}
+}
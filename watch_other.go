@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package dayone
+
+import (
+	"errors"
+)
+
+// startNative isn't supported on this platform so
+// Watch always falls back to polling.
+func (w *Watcher) startNative() error {
+	return errors.New("native watching not supported")
+}
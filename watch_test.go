@@ -0,0 +1,94 @@
+package dayone
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testWatch(t *testing.T, opts *WatchOptions) {
+	j, cleanup := copyJournal(t, "./test_journals/default")
+	defer cleanup()
+
+	w, err := j.Watch(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	next := func() Event {
+		select {
+		case ev := <-w.Events:
+			return ev
+		case err := <-w.Errors:
+			t.Fatal(err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+		return Event{}
+	}
+
+	// Several writes in a burst collapse into one event.
+	e := &Entry{EntryText: "one"}
+	for i := 0; i < 3; i++ {
+		if err := j.Write(e); err != nil {
+			t.Fatal(err)
+		}
+		e.EntryText += " more"
+	}
+
+	if ev := next(); ev.Type != EntryCreated || ev.UUID != e.UUID() {
+		t.Errorf("unexpected event: %v", ev)
+	}
+
+	e.EntryText = "changed"
+	if err := j.Write(e); err != nil {
+		t.Fatal(err)
+	}
+	if ev := next(); ev.Type != EntryModified || ev.UUID != e.UUID() {
+		t.Errorf("unexpected event: %v", ev)
+	}
+
+	if err := j.WritePhoto(e.UUID(), strings.NewReader("jpeg")); err != nil {
+		t.Fatal(err)
+	}
+	if ev := next(); ev.Type != PhotoAdded || ev.UUID != e.UUID() {
+		t.Errorf("unexpected event: %v", ev)
+	}
+
+	if err := os.Remove(filepath.Join(j.getPhotosDir(), e.UUID()+photoExt)); err != nil {
+		t.Fatal(err)
+	}
+	if ev := next(); ev.Type != PhotoRemoved || ev.UUID != e.UUID() {
+		t.Errorf("unexpected event: %v", ev)
+	}
+
+	if err := os.Remove(filepath.Join(j.getEntriesDir(), e.UUID()+entryExt)); err != nil {
+		t.Fatal(err)
+	}
+	if ev := next(); ev.Type != EntryDeleted || ev.UUID != e.UUID() {
+		t.Errorf("unexpected event: %v", ev)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Error(err)
+	}
+
+	if _, ok := <-w.Events; ok {
+		t.Error("expected events to be closed")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	testWatch(t, &WatchOptions{Debounce: 50 * time.Millisecond})
+}
+
+func TestWatchPolling(t *testing.T) {
+	testWatch(t, &WatchOptions{
+		Debounce:     50 * time.Millisecond,
+		Poll:         true,
+		PollInterval: 10 * time.Millisecond,
+	})
+}